@@ -0,0 +1,278 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package client runs commands on mote servers, for Go programs that
+// would otherwise run the mote command (or ssh, scp, and gomote).
+// It uses the mote command's transports, upload cache, aliases, and
+// passwords: a server that “mote @name cmd” can reach, Dial can too.
+//
+// A Conn runs a single command:
+//
+//	conn, err := client.Dial("kremvax")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	defer conn.Close()
+//	files, err := client.UploadList("./mypkg.test", nil, true)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	w, err := conn.Run(ctx, &client.Exec{
+//		Args:   []string{"./mypkg.test", "-test.short"},
+//		Files:  files,
+//		Stdout: os.Stdout,
+//		Stderr: os.Stderr,
+//	})
+//
+// The tail:// transport needs the mote command installed on $PATH,
+// which runs the Tailscale node in the background.
+package client
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"rsc.io/cmd/mote/internal/mote"
+)
+
+// A Conn is a connection to a mote server.
+type Conn struct {
+	GOOS    string   // the server's operating system
+	GOARCH  string   // the server's architecture
+	Level   string   // the server CPU's GOAMD64, GOARM64, or GOARM level, if known
+	Version int      // the server's protocol version
+	Caps    []string // the optional features the server supports
+
+	c *mote.Conn
+}
+
+// Dial connects to the server named by server, which is either a URL
+// (ssh://host, tcp://host:port, ws://host:port/path, wss://host/path,
+// tail://host, gomote://builder, exec://command line,
+// qemu://linux-goarch, unix:///path, local://) or a name,
+// resolved as by the mote command (see Resolve).
+// For an alias, Dial follows the alias's Fallback and Hex settings
+// in config.json; its settings for commands, like Env and Timeout,
+// are left to the caller.
+func Dial(server string) (*Conn, error) {
+	c, err := mote.Dial(server)
+	if err != nil {
+		return nil, err
+	}
+	return newConn(c), nil
+}
+
+// NewConn returns a connection to the server at the other end of rwc,
+// a byte stream from a transport of the caller's own. The password
+// encrypts the connection, as for tcp:// URLs; "" means the transport
+// is already secure.
+func NewConn(rwc io.ReadWriteCloser, password string) (*Conn, error) {
+	c, err := mote.NewConn(rwc, password)
+	if err != nil {
+		return nil, err
+	}
+	return newConn(c), nil
+}
+
+func newConn(c *mote.Conn) *Conn {
+	return &Conn{GOOS: c.GOOS, GOARCH: c.GOARCH, Level: c.Level, Version: c.Version, Caps: c.Caps, c: c}
+}
+
+// Close closes the connection.
+func (c *Conn) Close() error {
+	return c.c.Close()
+}
+
+// Resolve returns the URL of the server for name, as the mote command
+// resolves @name: a URL is itself, and other names are aliases (see
+// “mote alias”) or, with gomote installed, goos-goarch builder names.
+// An empty name means $MOTE, or else $GOOS-$GOARCH, or else the
+// GOOS-GOARCH of cmd, when cmd names a Go binary.
+func Resolve(name, cmd string) (string, error) {
+	return mote.ResolveServer(name, mote.CmdFile(cmd))
+}
+
+// A File is a file to place on the server.
+type File struct {
+	Path string // absolute path on the client, in slash form
+	Hash string // hex SHA-256 of the content
+	Size int64  // size in bytes
+}
+
+// UploadList returns the files to upload for running cmd, as the mote
+// command computes them: cmd itself, if it names a file (contains a
+// slash), the files and directory trees named by extra (like -u), and,
+// if testdata is set, the testdata directories from the current
+// directory up to the Go module root (like -t).
+func UploadList(cmd string, extra []string, testdata bool) ([]*File, error) {
+	list, err := mote.UploadList(mote.CmdFile(cmd), extra, testdata)
+	if err != nil {
+		return nil, err
+	}
+	var files []*File
+	for _, f := range list {
+		files = append(files, &File{Path: f.Path, Hash: f.Hash, Size: f.Size})
+	}
+	return files, nil
+}
+
+// An Exec describes a command to run on a server.
+type Exec struct {
+	// Args holds the command and its arguments. A command containing
+	// a slash names a file on the client, which must be in Files;
+	// any other command is looked up on the server's $PATH.
+	Args []string
+
+	// Dir is the client directory that the command runs in the server's
+	// copy of. The empty string means the current directory.
+	Dir string
+
+	// Files are the files to place on the server, at their client paths
+	// in a temporary directory tree. See UploadList.
+	Files []*File
+
+	// Env holds environment variables to add to the server's own.
+	Env []string
+
+	// Stdin is the command's standard input; nil means none.
+	// If Stdin is not nil, Run reads it in a goroutine that may
+	// continue after Run returns, until Stdin reaches EOF or the
+	// connection is closed.
+	Stdin io.Reader
+
+	// Stdout and Stderr receive the command's output; nil discards it.
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// Exclusive asks for sole use of the server, for benchmarking,
+	// like the mote command's -exclusive flag.
+	Exclusive bool
+
+	// Link says how the server places Files: "copy", "clone" (the
+	// default), or "hard", like the mote command's -link flag.
+	Link string
+
+	// Forwards lists ports to forward while the command runs,
+	// like the mote command's -L and -R flags.
+	Forwards []Forward
+
+	// CoreDir, if set, asks the server to run the command with
+	// GOTRACEBACK=crash and core dumps enabled and, if it crashes,
+	// to send back the core dump, which Run saves in CoreDir along
+	// with the binary, like the mote command's -core flag.
+	CoreDir string
+
+	// PathEnv lists environment variables, like Env, whose values
+	// are client paths, or file:// URLs naming them, which the
+	// server maps into its copy of the uploaded tree, as it does Dir.
+	PathEnv []string
+}
+
+// A Forward is a port to forward while a command runs.
+// Each connection to Port on the loopback interface of the listening
+// end, the client or (if Remote is set) the server, is connected to
+// Addr from the other end.
+type Forward struct {
+	Remote bool   // listen on the server, like -R, instead of the client, like -L
+	Port   string // port to listen on
+	Addr   string // host:port to connect to
+}
+
+// A Wait describes how a command finished.
+type Wait struct {
+	Code   int           // exit code, or a negative number if killed by a signal
+	Status string        // description of the exit, like "exit status 1"
+	Waited time.Duration // time spent waiting for exclusive use of the server
+	Usage  *Usage        // resources the command used; nil if the server does not say
+	Core   string        // path of the saved core dump, if Exec.CoreDir was set and one arrived
+}
+
+// A Usage describes the resources a command used on the server,
+// as the mote command's -stats flag prints them.
+type Usage struct {
+	Wall   time.Duration // time the command ran
+	User   time.Duration // user CPU time
+	System time.Duration // system CPU time
+	MaxRSS int64         // maximum resident set size in bytes, or 0 if the system does not say
+	Output int64         // bytes of standard output and standard error
+}
+
+// Run runs the command described by e and waits for it to finish.
+// A Conn runs only one command.
+//
+// If ctx is done before the command starts, Run stops and returns
+// ctx.Err(). If ctx is done while the command runs, Run kills it
+// and returns the Wait reporting its end.
+//
+// If the server is too old to support a feature that e asks for
+// (see Conn.Caps), Run writes a warning to e.Stderr and runs the
+// command without it.
+//
+// The returned error reports only a failure to run the command
+// (or to hear how it finished); a command that runs and fails
+// is reported by the Wait.
+func (c *Conn) Run(ctx context.Context, e *Exec) (*Wait, error) {
+	args := append([]string(nil), e.Args...)
+	if len(args) > 0 {
+		args[0] = mote.CmdFile(args[0])
+	}
+	dir := e.Dir
+	if dir == "" {
+		var err error
+		if dir, err = os.Getwd(); err != nil {
+			return nil, err
+		}
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []*mote.File
+	for _, f := range e.Files {
+		files = append(files, &mote.File{Path: f.Path, Hash: f.Hash, Size: f.Size})
+	}
+	var forwards []*mote.Forward
+	for _, f := range e.Forwards {
+		forwards = append(forwards, &mote.Forward{Remote: f.Remote, Listen: f.Port, Dial: f.Addr})
+	}
+	w, err := c.c.Run(ctx, &mote.Exec{
+		Args:      args,
+		Dir:       filepath.ToSlash(dir),
+		Files:     files,
+		Env:       e.Env,
+		Stdin:     e.Stdin,
+		Stdout:    e.Stdout,
+		Stderr:    e.Stderr,
+		Exclusive: e.Exclusive,
+		Link:      e.Link,
+		Forwards:  forwards,
+		CoreDir:   e.CoreDir,
+		PathEnv:   e.PathEnv,
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		return nil, c.c.Abort(err)
+	}
+	wait := &Wait{Code: w.Code, Status: w.Status, Waited: w.Waited, Core: w.Core}
+	if u := w.Usage; u != nil {
+		wait.Usage = &Usage{Wall: u.Wall, User: u.User, System: u.System, MaxRSS: u.MaxRSS, Output: u.Output}
+	}
+	return wait, nil
+}
+
+// Run dials server, runs the command described by e there, and closes
+// the connection. See Dial and Conn.Run.
+func Run(ctx context.Context, server string, e *Exec) (*Wait, error) {
+	c, err := Dial(server)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	return c.Run(ctx, e)
+}
@@ -0,0 +1,97 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"rsc.io/cmd/mote/internal/mote"
+)
+
+// pipeConn returns a Conn to a server session on an in-memory pipe.
+func pipeConn(t *testing.T) *Conn {
+	t.Helper()
+	t.Setenv("MOTECONFIG", t.TempDir())
+	t.Setenv("MOTECACHE", t.TempDir())
+	cconn, sconn := net.Pipe()
+	go func() {
+		mote.Serve(sconn, "")
+		sconn.Close()
+	}()
+	t.Cleanup(func() { cconn.Close() })
+	c, err := NewConn(cconn, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestRun(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	dir := t.TempDir()
+	t.Chdir(dir)
+	if err := os.WriteFile("x.sh", []byte("#!/bin/sh\necho $GREETING; cat; exit 3\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	files, err := UploadList("./x.sh", nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Path != filepath.ToSlash(filepath.Join(dir, "x.sh")) {
+		t.Fatalf("UploadList = %+v", files)
+	}
+
+	c := pipeConn(t)
+	if c.GOOS != runtime.GOOS || c.GOARCH != runtime.GOARCH {
+		t.Errorf("server is %s-%s, want %s-%s", c.GOOS, c.GOARCH, runtime.GOOS, runtime.GOARCH)
+	}
+	var outb bytes.Buffer
+	w, err := c.Run(t.Context(), &Exec{
+		Args:   []string{"./x.sh"},
+		Files:  files,
+		Env:    []string{"GREETING=hello"},
+		Stdin:  strings.NewReader("input\n"),
+		Stdout: &outb,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != 3 || outb.String() != "hello\ninput\n" {
+		t.Errorf("Run: code=%d stdout=%q, want 3, %q", w.Code, outb.String(), "hello\ninput\n")
+	}
+}
+
+func TestRunCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	_, err := pipeConn(t).Run(ctx, &Exec{Args: []string{"echo", "hi"}})
+	if err != context.Canceled {
+		t.Errorf("Run with canceled context = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	t.Setenv("MOTECONFIG", t.TempDir())
+	t.Setenv("MOTE", "")
+	if url, err := Resolve("ssh://kremvax", ""); err != nil || url != "ssh://kremvax" {
+		t.Errorf("Resolve(URL) = %q, %v", url, err)
+	}
+	t.Setenv("MOTE", "tcp://kremvax:1234")
+	if url, err := Resolve("", ""); err != nil || url != "tcp://kremvax:1234" {
+		t.Errorf("Resolve(\"\") with $MOTE = %q, %v", url, err)
+	}
+	if _, err := Resolve("nosuchalias", ""); err == nil {
+		t.Errorf("Resolve(nosuchalias) succeeded")
+	}
+}
@@ -8,11 +8,15 @@ Usage:
 
 	mote [-u path]... [@name] cmd [args...]
 	mote alias [name [URL]]
-	mote clean
+	mote clean [-n] [-older duration]
 	mote close [URL]
-	mote go-setup
+	mote discover [-y]
+	mote go-setup [-list | -remove]
+	mote info [-json] [@name]
 	mote login URL
 	mote serve URL
+	mote trace [-conn pid.n] file
+	mote trace -replay [-conn pid.n] [@name] file
 	mote version
 
 # Running Programs
@@ -39,6 +43,12 @@ Mote treats the command name as a file to be uploaded when it contains a slash:
 The name resolves to a file the way running it locally would,
 so on Windows “mote ./strings” uploads and runs ./strings.exe.
 
+Before uploading a binary, mote checks that the server can run it:
+a binary built for another system, or a Go binary built for a higher
+GOAMD64, GOARM64, or GOARM level than the server's CPU supports,
+stops with an error instead of an exec format error or a crash
+on an illegal instruction partway through a test.
+
 # Uploading Additional Files
 
 The command runs in a remote temporary directory that includes the local directory name.
@@ -64,6 +74,121 @@ if they exist, up to the current Go module root:
 
 	% mote -t @ssh://kremvax ./mypkg.test
 
+# Exclusive Use for Benchmarking
+
+A server runs commands from any number of clients at once, which is
+what most uses want but ruins benchmarks. The -exclusive flag asks the
+server for the machine to itself: the command waits for the commands
+already running to finish, and new ones wait until it exits.
+When it finishes, mote reports how long it waited:
+
+	% mote -exclusive @kremvax ./mypkg.test -test.bench=.
+	...
+	mote: waited 4.2s for exclusive use of server
+	%
+
+Commands are kept apart across all the servers running as the same
+user on the machine, whatever transport reached them.
+
+# Forwarding Ports
+
+A test may need a service that only the client can reach, such as a
+fake object store or a database running on the developer's machine,
+or the client may need to reach a service the command starts. Like
+ssh, mote forwards ports for the command while it runs, carrying the
+connections inside its own connection to the server.
+
+The repeatable -R flag, -R port:host:hostport, has the server listen
+on port on its loopback interface and connects each connection there
+to host:hostport from the client:
+
+	% mote -R 9000:localhost:9000 @kremvax ./mypkg.test
+
+The repeatable -L flag, -L port:host:hostport, has the client listen
+on port on its loopback interface and connects each connection there
+to host:hostport from the server:
+
+	% mote -L 6060:localhost:6060 @kremvax ./myserver
+
+Forwarding begins when the command starts and ends when it exits,
+closing any connections still open.
+
+# Resource Usage
+
+The -stats flag prints what the remote command used once it exits:
+the time it ran, its user and system CPU time, its maximum resident
+set size, and the bytes of output it wrote. Comparing them across
+servers shows how a test binary's memory behavior differs from one
+architecture to another:
+
+	% mote -stats @linux-arm64 ./mypkg.test
+	PASS
+	mote: wall 2.315s, user 3.02s, system 211ms, max RSS 48.6 MB, output 5 bytes
+	%
+
+Windows servers do not report the maximum resident set size.
+
+# Crash Dumps
+
+A test binary that crashes on a server leaves only its output behind.
+The -core flag runs the command with GOTRACEBACK=crash and core dumps
+enabled; if it dies with a signal, the server finds the core file,
+in the command's directory or wherever the system keeps core files
+(following core_pattern and systemd-coredump on Linux, or in /cores
+on macOS), and sends it back with the binary that crashed. Mote saves
+the two in the given directory, as name.core and name:
+
+	% mote -core /tmp/crash @linux-arm64 ./mypkg.test
+	panic: runtime error: invalid memory address or nil pointer dereference
+	...
+	mote: saved core dump in /tmp/crash/mypkg.test.core
+	% dlv core /tmp/crash/mypkg.test /tmp/crash/mypkg.test.core
+
+Whether a crash leaves a core file at all is up to the server's
+system: its hard limit on core file size, and where core_pattern
+sends them, must allow one. Windows servers send no core dumps.
+
+# Rerunning on Changes
+
+The -watch flag keeps mote running after the command exits, watching
+the command's binary and the files that -u and -t upload. Whenever
+they change, mote runs the command again on the same server,
+stopping the previous run first if it is still going. Only the
+changed files are uploaded again; the server has the rest cached.
+Mote waits for the files to stop changing before it reruns, so that
+a rebuild in another window is finished first:
+
+	% mote -watch @linux-riscv64 ./mypkg.test -test.run=TestFoo
+	--- FAIL: TestFoo (0.01s)
+	...
+	mote: remote command failed: exit status 1
+	mote: waiting for changes
+	mote: files changed; running again
+	PASS
+
+An interrupt stops the current run and ends the watch.
+
+# Placing Uploaded Files
+
+The server keeps uploaded files in a cache and gives each command its
+own copies, so that nothing a command does can damage the cache.
+Copying multi-hundred-megabyte binaries and testdata for every command
+takes time, though, so by default the server makes copy-on-write
+clones instead where its file system supports them (Btrfs and XFS on
+Linux, for example), which are instant and just as safe. The -link
+flag chooses how files are placed: -link=copy always copies, and
+-link=hard, for commands that do not write to their files, makes
+read-only hard links to the cache when it cannot clone.
+
+	% mote -link=hard @kremvax ./mypkg.test
+
+# Go Programs
+
+Go programs can run commands on mote servers without running the mote
+command, using the package rsc.io/cmd/mote/client, which reuses mote's
+transports, aliases, and upload cache, and adds standard input,
+cancellation by context, and a per-command environment.
+
 # Server Aliases and Server Selection
 
 The “mote alias” command defines an alias for a URL:
@@ -87,7 +212,7 @@ Running “mote alias” without any arguments lists all the known aliases:
 	%
 
 Each time a mote command runs, it discovers the GOOS and GOARCH of the remote system.
-If there is no alias named $GOOS-$GOARCH already, mote adds one resolving to that system.
+If there is no alias or group named $GOOS-$GOARCH already, mote adds one resolving to that system.
 In this case, mote has defined a linux-amd64 alias.
 
 If the “@server” is omitted, mote tries three fallbacks, in order:
@@ -102,6 +227,84 @@ Demonstrating all three:
 	% GOOS=linux GOARCH=amd64 mote ./mypkg.test
 	% mote ./mypkg.test
 
+A $GOOS-$GOARCH name with no alias falls back to a new gomote, if the
+gomote command is installed (see “Using Gomotes” below), and otherwise,
+for linux, to emulation on the local machine, if it is possible (see
+“Using QEMU” below).
+
+# Alias Settings
+
+Aliases live in config.json in the configuration directory (see
+“Configuration” below), which can also give each alias settings for
+the commands run through it. For example:
+
+	{
+		"Aliases": {
+			"kremvax": {
+				"URL": "tcp://kremvax:6683",
+				"Fallback": ["ssh://kremvax"],
+				"Group": "linux-amd64",
+				"Env": ["GOFLAGS=-count=1", "TMPDIR=/scratch"],
+				"Exclude": ["*.log", "testdata/huge"],
+				"Timeout": "10m"
+			},
+			"phone": {
+				"URL": "ssh://phone",
+				"Hex": true
+			}
+		}
+	}
+
+The settings are:
+
+  - URL is the server URL, the one “mote alias” sets.
+  - Fallback lists more URLs for the same server, tried in order when
+    URL cannot be reached.
+  - Group names a group the alias belongs to (see “Server Groups” below).
+  - Env lists environment variables to set for commands.
+  - Exclude lists patterns (as in path.Match) naming files not to
+    upload from -u and testdata directories. A pattern with no slash
+    matches file and directory names anywhere in the tree; a pattern
+    with a slash matches paths relative to the top of the tree.
+  - Timeout limits how long a command may run, from the start of its
+    upload. A command that runs too long is killed.
+  - Hex runs ssh:// and exec:// servers in hex (see “Using Other
+    Commands” below), for connections that are not binary safe;
+    the URLs become ssh+hex:// and exec+hex://.
+
+Older versions of mote kept aliases in aliases.txt, one name and URL
+per line. Mote moves them to config.json the first time it runs,
+leaving the old file as aliases.txt.old.
+
+# Server Groups
+
+When several machines can run the same commands, such as a pool of
+identical linux-amd64 machines, aliases for them can share a Group
+setting. The group's name then names them all, wherever an alias
+could be used:
+
+	{
+		"Aliases": {
+			"kremvax1": {"URL": "tcp://kremvax1:6683", "Group": "linux-amd64"},
+			"kremvax2": {"URL": "tcp://kremvax2:6683", "Group": "linux-amd64"},
+			"kremvax3": {"URL": "ssh://kremvax3", "Group": "linux-amd64"}
+		}
+	}
+
+To run a command in a group, mote connects to all its servers at once.
+Each server reports its load, the number of mote commands in progress
+on its machine. Mote runs the command on the least loaded server among
+those that answer within half a second of the first, choosing at random
+among equally loaded ones, and hangs up on the rest. Servers that
+cannot be reached are skipped. A group named $GOOS-$GOARCH, like the one
+above, spreads “go test -exec mote” across the group, since each test
+binary makes a choice of its own. The settings of the chosen server's
+alias apply to the command.
+
+Where a single URL is needed, as for “mote close”, a group name
+means the group's first alias, in name order. An alias of the same
+name as a group takes precedence over the group.
+
 # Go Run and Go Test Integration
 
 The Go toolchain handles “go run” and “go test” of cross-compiled binaries by
@@ -121,6 +324,23 @@ The runner script is “mote -t” applied to the arguments:
 	exec mote -t "$@"
 	%
 
+On Windows, where the go command cannot run a shell script, the
+runners are batch files, like go_linux_amd64_exec.cmd, holding
+“@mote -t %*”. The go command finds them by the extensions in %PATHEXT%.
+
+Mote records the runners it installs, in its configuration, and
+“mote go-setup -remove” removes them again, leaving any that have
+been edited since, still recorded as mote's. “mote go-setup -list” shows each GOOS/GOARCH
+combination's runner, marking those that mote installed, and the
+server its tests would run on, by the $GOOS-$GOARCH alias or group,
+a gomote, or local emulation:
+
+	% mote go-setup -list
+	...
+	linux/arm64    /home/rsc/bin/go_linux_arm64_exec (mote)    alias linux-arm64 = ssh://pi
+	linux/riscv64  /home/rsc/bin/go_linux_riscv64_exec (mote)  qemu://linux-riscv64
+	...
+
 The script names no server, so mote uses the fallbacks listed above.
 Cross-compiling on the command line sets $GOOS and $GOARCH in the
 environment that “go test” passes to the script, selecting the alias for
@@ -141,6 +361,36 @@ or a URL to use instead:
 
 Setting $MOTE is useful when more than one server runs the same $GOOS-$GOARCH.
 
+# Testing from Source
+
+Inside a Go module, “mote go test” runs go test on the server, with
+the server's own Go toolchain, instead of a test binary built on the
+client. That tests the server's toolchain, not just its system, and
+suits servers that the client's toolchain cannot build for:
+
+	% mote @kremvax go test -short ./...
+	ok  	example.com/hello	0.012s
+	ok  	example.com/hello/greeting	0.004s
+	%
+
+Mote uploads the module's source tree, leaving out version control
+directories and anything the alias's Exclude patterns match, along
+with what the build needs from outside the module. A module with a
+vendor directory needs nothing more. Otherwise, mote uploads the
+directories of local replacements and, from the client's module
+cache, the downloaded files of every module in the build list and
+the go.mod files of the other versions in the module graph, which
+the go command reads when a module predates Go 1.17, and runs the
+command with GOFLAGS=-mod=mod and GOPROXY set to the uploaded copy
+of the cache, so that the server builds with exactly the client's
+dependencies and never needs the network. A module
+missing from the client's cache is an error; “go mod download”
+fetches it. The command runs with GOTOOLCHAIN=local, using whatever
+Go the server has, and with GOWORK=off.
+
+Outside a module, and for go commands other than go test, mote runs
+the server's go command as it is, like any other command.
+
 # Using SSH
 
 To use mote over SSH, compile and install mote on both client and server, and check that it is available on the server PATH:
@@ -151,6 +401,19 @@ To use mote over SSH, compile and install mote on both client and server, and ch
 	kremvax.uucp
 	%
 
+Installing mote on a Unix server is optional. With $MOTEUPGRADE=1 set,
+when the server reports that it has no mote command, the client asks it
+for its system and architecture (using “uname -sm”), cross-compiles
+mote for it, which requires the Go toolchain on the client, installs it
+on the server as ~/.cache/mote/bin/mote, and runs that one. Later runs
+reuse the installed mote, replacing it only once it is older than the
+client. The copy replaces the installed mote only once its size, and
+its SHA-256 hash if the server has sha256sum or shasum, match the
+binary sent. Without $MOTEUPGRADE, the client installs nothing and
+reports the missing command. Nor does it install mote, or upgrade it,
+over ssh+hex:// (see “Using Other Commands” below), whose connections
+cannot be trusted to carry a binary intact.
+
 Mote runs ssh with persistence enabled with a 30-minute timeout,
 so that repeated uses of mote can share a long-running ssh connection.
 Specifically, it runs:
@@ -162,6 +425,15 @@ Specifically, it runs:
 		kremvax \
 		mote serve -
 
+When the client is newer than the mote installed on the server, it
+runs commands anyway, warning about any features (such as -exclusive)
+that the older server does not support. Setting $MOTEUPGRADE=1 asks
+the client to upgrade such a server instead: it cross-compiles a mote
+matching its own and installs it on the server as
+~/.cache/mote/bin/mote, as for a server with no mote at all, and runs
+that one from then on, until the server's own mote catches up.
+Windows servers are not upgraded.
+
 # Using Tailscale
 
 Using mote over SSH requires that the server be directly accessible.
@@ -202,6 +474,20 @@ Once the system is registered, either by a previous “mote serve”
 or an explicit “mote login”, “mote serve tail://servername”
 can be shortened to “mote serve tail:”.
 
+To find the mote servers on the tailnet without knowing their names:
+
+	mote discover
+
+Discover connects to each node tagged tag:mote, lists the servers
+with their GOOS, GOARCH, and protocol version, and offers to create
+an alias for each server by its name and a $GOOS-$GOARCH alias for
+each system: the server itself, or a group (see “Server Groups”) of
+the servers when there are several. An existing alias for a server's
+tail:// URL, by the server's name, joins the group with its settings
+intact; other existing aliases are left alone. The -y flag creates
+the aliases without asking.
+Clients are tagged too, so discover also lists them, as not serving.
+
 Bringing up a Tailscale node takes a few seconds, and Tailscale does not
 expect nodes to come and go frequently, so mote keeps the node running
 in a background daemon, the same way ssh keeps a connection.
@@ -251,6 +537,58 @@ To run a remote command:
 Each server has its own password: logging in to a second server adds
 an entry instead of replacing the first.
 
+# Using WebSockets
+
+A server that clients can reach only over HTTP, such as one behind a
+reverse proxy or a firewall that passes only web traffic, can serve
+WebSocket connections instead, at a URL of the form ws://host:port/path.
+The client dials ws:// URLs, or wss:// URLs when the proxy in front of
+the server terminates TLS, through any HTTP proxy named by the
+$HTTPS_PROXY, $HTTP_PROXY, and $NO_PROXY environment variables.
+As with direct TCP, client and server share a password, saved by
+“mote login”, which authenticates and encrypts the connection end to
+end, so the proxies in between see only an encrypted stream:
+
+	% mote login ws://:8080/mote
+	password for ws://:8080/mote:
+	mote: wrote password for ws://:8080/mote to /home/rsc/.config/mote/password.txt
+	% mote serve ws://:8080/mote
+	mote: serving ws://kremlsun:8080/mote
+
+	% mote login wss://mote.example.com/kremlsun
+	password for wss://mote.example.com/kremlsun:
+	mote: wrote password for wss://mote.example.com/kremlsun to /home/rsc/.config/mote/password.txt
+	% mote @wss://mote.example.com/kremlsun hostname
+	kremlsun.arpa
+	%
+
+The server itself speaks only plain HTTP: it cannot serve wss:// URLs,
+which need a proxy in front of it to handle TLS.
+
+# Using Local Servers
+
+A server on the same machine can listen on a Unix domain socket,
+named by a unix:///path URL, with no password:
+
+	% mote serve unix:///home/rsc/.cache/mote.sock
+	mote: serving unix:///home/rsc/.cache/mote.sock
+
+	% mote @unix:///home/rsc/.cache/mote.sock hostname
+	kremlsun.arpa
+	%
+
+The server makes the socket accessible only to its own user, and on
+Linux it also refuses connections from processes of any other user.
+
+The local:// URL needs no server at all: mote runs the server side of
+the session itself, in the same process, so the command runs on this
+machine in a temporary copy of the uploaded files, exactly as a server
+would run it. That is useful in scripts and for trying things out.
+
+	% mote @local:// ./mypkg.test
+	PASS
+	%
+
 # Using Gomotes
 
 The Go project runs a custom remote execution facility known as gomotes,
@@ -279,6 +617,116 @@ backed by gomotes as needed. For example:
 	gomote://gotip-linux-arm64
 	%
 
+# Using QEMU
+
+For a quick check on another Linux architecture, with no server for it
+at hand, a qemu://linux-goarch URL runs the command on the local Linux
+machine, under QEMU's user-mode emulation:
+
+	% GOOS=linux GOARCH=riscv64 go test -c
+	% mote @qemu://linux-riscv64 ./mypkg.test
+	PASS
+	%
+
+The command still runs in a temporary copy of the uploaded files, the
+same as on any server, so testdata and relative paths behave the same.
+Uploaded programs run under the emulator: the one registered with the
+kernel's binfmt_misc for the architecture, if there is one, and
+otherwise qemu-goarch (qemu-riscv64, qemu-aarch64, and so on) or its
+-static variant, found on $PATH. The binfmt_misc setup is better,
+because programs that run other programs for the architecture (such
+as a test running itself) work too. Commands that were not uploaded
+are the local machine's own and run natively.
+
+With no alias or gomote for a linux-goarch name, such as the one
+GOOS=linux GOARCH=riscv64 go test asks for, mote falls back to
+qemu://linux-goarch if it can emulate that architecture. Unlike a
+gomote, the fallback is not saved as an alias, so that an alias or
+server added later takes over; nor is there a fallback for the local
+machine's own architecture, which needs no emulation.
+
+# Using Other Commands
+
+An exec:// URL reaches a server through any command whose standard
+input and output lead to “mote serve -” somewhere: a container, a
+phone, a board on a serial line. The URL is exec:// followed by the
+command line, which is split into words at spaces, with quoting as in
+the shell but no other shell syntax. Aliases save the typing:
+
+	% mote alias pod exec://kubectl exec -i mypod -- mote serve -
+	% mote alias box 'exec://docker exec -i box mote serve -'
+	% mote @pod hostname
+	mypod
+	%
+
+Some channels, like adb shell and serial consoles, do not carry every
+byte unchanged. For those, use exec+hex:// instead, with a command
+line that runs “mote serve -hex-” on the far end: the protocol then
+travels hex-encoded, which any terminal passes through.
+
+	% mote alias phone 'exec+hex://adb shell /data/local/tmp/mote serve -hex-'
+
+The same goes for ssh: an ssh+hex:// URL runs “mote serve -hex-” on
+the server, for ssh connections that pass through something that is
+not binary safe.
+
+# Describing Servers
+
+“mote info” connects to a server and describes its machine: the
+system, CPU level, protocol version and capabilities, and load that
+every server reports, and, from servers that support it, the mote
+version, operating system, CPU model and features, memory, Go
+toolchain, upload cache, and how far its clock is from the local one.
+
+	% mote info @kremvax
+	server   ssh://kremvax
+	system   linux-amd64 (GOAMD64=v3)
+	protocol version 4 (stdin exclusive link forward stats info)
+	load     0 commands
+	mote     v0.0.0-20260612181518-3b4c7e2a9f10
+	os       Debian GNU/Linux 12 (bookworm), Linux 6.1.0-21-amd64
+	cpu      AMD Ryzen 9 7950X 16-Core Processor, 32 CPUs
+	features aes adx avx avx2 bmi1 bmi2 fma popcnt sse41 sse42 ...
+	memory   67.2 GB
+	go       go version go1.26.2 linux/amd64
+	cache    214 files, 1630.4 MB, unused files kept 24h0m0s
+	clock    +3ms relative to this machine
+
+With no @name, it describes the default server. The Go toolchain is
+the go command that commands run through the alias would find, on the
+PATH its Env settings give them. The -json flag prints the same
+information as JSON, for scripts.
+
+# Monitoring Servers
+
+The -metrics flag makes a tcp://, tail://, unix://, or ws:// server serve
+metrics over HTTP, in the Prometheus text format, at /metrics on the
+given address:
+
+	% mote -metrics localhost:9683 serve tcp://:6683
+	mote: serving tcp://kremlsun:6683
+	mote: serving metrics at http://127.0.0.1:9683/metrics
+
+The metrics count sessions (active and total), failed handshakes by
+reason, bytes uploaded, cache hits and misses, the cache's size on
+disk, and the number, failures, and total running time of commands.
+
+# Stopping Servers
+
+A tcp://, unix://, or ws:// server stopped by SIGTERM or an interrupt
+shuts down gracefully. It stops accepting connections and ends the
+sessions whose commands have not yet started, but lets the commands
+already running finish, telling their clients that the server is
+shutting down. Commands still running after the -drain time, one
+minute by default, are killed, and the server exits once every session
+has ended and cleaned up. A second signal kills the commands at once.
+
+	% mote -drain 10m serve tcp://:6683
+	mote: serving tcp://kremlsun:6683
+	^Cmote: interrupt: draining sessions for up to 10m0s
+	mote: drained
+	%
+
 # Closing Servers
 
 Each transport leaves the connection open for the next mote command,
@@ -301,6 +749,42 @@ that mote created.
 	mote: destroyed gomote user-gotip-linux-amd64-0
 	%
 
+# Tracing Sessions
+
+Setting $MOTETRACE to a file name makes mote append a record of every
+protocol packet it sends or receives to that file, on a client or a
+server: the time, the process and connection, the direction, the
+packet's JSON metadata, and the length of its data. Each record is a
+line of JSON. The data itself is not recorded, since it can hold
+secrets and program output, unless $MOTETRACEDATA is also set to 1:
+then the data of requests is recorded too, up to 64 kB per packet,
+except for uploads and handshake messages, so that a client session
+can be replayed exactly.
+
+“mote trace file” prints a trace, one packet per line, with the time
+in seconds since the first record, the process ID and connection
+number, and -> for a packet sent or <- for one received:
+
+	% MOTETRACE=/tmp/trace mote @phone ./mypkg.test
+	...
+	% mote trace /tmp/trace
+	  0.000000 8812.1 <- {"Type":"Info","GOOS":"android","GOARCH":"arm64",...}
+	  0.000412 8812.1 -> {"Type":"Setup","Files":[...],"Args":["/tmp/go-build/mypkg.test"],...}
+	  0.001287 8812.1 <- {"Type":"Need","Need":["9f2c..."]}
+	  0.001301 8812.1 -> {"Type":"Upload"} +3012608 bytes
+	...
+
+The -conn flag limits the output to one connection. With -replay,
+mote trace connects to the server and sends it the requests of a client
+session from the trace (the first one, or the one -conn names), each
+after the server has sent as many responses as it had in the recording,
+printing the packets as it goes. The files to upload are read again
+from their paths on the client. Request data missing from the trace,
+such as standard input recorded without $MOTETRACEDATA, is replayed
+as zero bytes of the recorded length. Replaying a recording of a failed
+session against the same server, or a different one, reproduces a
+protocol bug without the program that first hit it.
+
 # Configuration
 
 Mote stores its configuration in a mote subdirectory
@@ -312,7 +796,9 @@ of the user configuration directory. For example:
 
 In that directory:
 
-  - aliases.txt contains the alias definitions, one alias per line.
+  - config.json contains the alias definitions and their settings
+    (see “Alias Settings” above), and the list of runners that
+    “mote go-setup” installed.
   - password.txt contains the passwords shared with tcp:// servers,
     as written by “mote login”: one line per server, holding the server
     URL and then the password, separated by a space.
@@ -330,7 +816,22 @@ mote/cache subdirectory of the user cache directory
 (for example, /home/rsc/.cache/mote/cache on Linux).
 Setting $MOTECACHE overrides the location of the cache directory.
 Each time a command finishes, the server deletes cached files that
-have gone unused for more than three hours.
+have gone unused for more than three hours, or for the duration set
+by $MOTECACHEAGE (for example, MOTECACHEAGE=24h).
+Setting $MOTECACHESIZE (for example, MOTECACHESIZE=10G) also limits
+the size of the cache: when the cache grows beyond that size, the
+server deletes the least recently used files until it fits again.
+Cleaning never deletes the files of a command that is still being set
+up, even one being set up by a different server process: it waits
+for the next command to finish instead.
+
 Running “mote clean” deletes the entire cache.
+The -older flag deletes only the files unused for longer than the
+given duration, and the -n flag prints the files that would be deleted
+without deleting them:
+
+	% mote clean -n -older 24h
+	/home/rsc/.cache/mote/cache/3f/3f9a...
+	% mote clean -older 24h
 */
 package main
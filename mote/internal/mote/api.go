@@ -0,0 +1,49 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mote
+
+import "io"
+
+// The entry points below are for rsc.io/cmd/mote/client, which wraps
+// them (and Conn, Exec, Wait, and File) in an API of its own, leaving
+// this package free to change. See that package for documentation.
+
+// Dial resolves the server name as the mote command does and
+// connects to the server, trying the alias's fallback URLs in turn
+// and choosing the least loaded server in a group.
+func Dial(name string) (*Conn, error) {
+	list, err := resolveAliases(name, "")
+	if err != nil {
+		return nil, err
+	}
+	c, _, _, err := dialAliases(list)
+	return c, err
+}
+
+// NewConn runs the client side of the connection handshake on rwc.
+func NewConn(rwc io.ReadWriteCloser, password string) (*Conn, error) {
+	return clientConn(rwc, password)
+}
+
+// Abort tears down c after a failed Run, adding any transport
+// diagnostics to err.
+func (c *Conn) Abort(err error) error { return c.abort(err) }
+
+// ResolveServer resolves a server name to a URL as the mote command does.
+func ResolveServer(name, cmd string) (string, error) { return resolveServer(name, cmd) }
+
+// CmdFile returns the file named by the command name.
+func CmdFile(name string) string { return cmdFile(name) }
+
+// UploadList returns the files to upload for the command.
+func UploadList(cmd string, extra []string, testdata bool) ([]*File, error) {
+	return uploadList(cmd, extra, testdata, nil)
+}
+
+// Serve serves one session on rw.
+// It is for testing the client package.
+func Serve(rw io.ReadWriteCloser, password string) error {
+	return serve(rw, password, nil)
+}
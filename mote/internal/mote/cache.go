@@ -0,0 +1,520 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mote
+
+import (
+	"crypto/sha256"
+	"debug/pe"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// cacheMaxAge is how long an unused cached file survives cleanCache,
+// unless $MOTECACHEAGE says otherwise.
+const cacheMaxAge = 3 * time.Hour
+
+// cmdClean implements "mote clean [-n] [-older duration]",
+// deleting the entire cache or the files in it unused for longer
+// than the -older duration. With -n, it prints the files it would
+// delete and deletes nothing.
+func cmdClean(args []string) {
+	flags := flag.NewFlagSet("clean", flag.ExitOnError)
+	flags.Usage = usage
+	dryRun := flags.Bool("n", false, "print the files to delete but do not delete them")
+	older := flags.Duration("older", 0, "delete only files unused for longer than `duration`")
+	flags.Parse(args)
+	if flags.NArg() != 0 {
+		usage()
+	}
+	// Without -older, every file goes.
+	maxAge := *older
+	if maxAge == 0 {
+		maxAge = -1
+	}
+	if !*dryRun {
+		// Wait for the sessions setting up to finish with their files.
+		release, err := lockCache(true)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer release()
+	}
+	entries, err := cacheEntries()
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, e := range cacheEvictions(entries, time.Now(), maxAge, 0) {
+		if *dryRun {
+			fmt.Printf("%s\n", e.path)
+			continue
+		}
+		if err := os.Remove(e.path); err != nil && !os.IsNotExist(err) {
+			log.Fatal(err)
+		}
+		os.Remove(filepath.Dir(e.path)) // if that emptied the shard
+	}
+}
+
+// cleanCache deletes cached files that have gone unused for longer
+// than the maximum age, and then, if the cache is larger than its
+// maximum size, the least recently used files until it fits.
+// (inCache updates the modification time of the files it finds,
+// so recently used files are safe, and they are the last to go.)
+//
+// If a session is copying a file out of the cache at that moment,
+// cleanCache leaves it alone; the next command to finish will clean it
+// instead. Sessions hold the cache only that briefly (see holdCache).
+func cleanCache() {
+	release, err := lockCache(false)
+	if err != nil {
+		return
+	}
+	defer release()
+	entries, err := cacheEntries()
+	if err != nil {
+		return
+	}
+	maxAge, maxSize := cacheLimits()
+	for _, e := range cacheEvictions(entries, time.Now(), maxAge, maxSize) {
+		os.Remove(e.path)
+	}
+}
+
+// Sharing the cache.
+//
+// A session looks for each of its files in the cache and copies it
+// into its temporary tree, and then receives the missing ones and does
+// the same with them. If cleanCache, which runs after every command in
+// every server, deleted a file between the looking and the copying,
+// the session would fail. So a session holds the cache, shared, while
+// it looks for and copies each file, and deleting files needs the
+// cache to itself. The hold is never kept while the session waits on
+// its client, so cleaning is not held up by slow uploads; a file
+// cleaned out before it could be copied is just received again. A
+// file lock extends this to servers in other processes, and cacheLock
+// orders the sessions in this one (on systems without file locks, it
+// is all there is).
+
+// cacheLock orders the uses of the cache in this process.
+var cacheLock sync.RWMutex
+
+// cacheLockPath returns the name of the file lock ordering uses of the
+// cache between processes.
+func cacheLockPath() (string, error) {
+	dir, err := cacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "cache.lock"), nil
+}
+
+// holdCache waits for a shared hold on the cache, returning a function
+// that releases it (and may be called more than once).
+func holdCache() (release func(), err error) {
+	path, err := cacheLockPath()
+	if err != nil {
+		return nil, err
+	}
+	cacheLock.RLock()
+	f, err := waitLockFile(path, false)
+	if err != nil {
+		cacheLock.RUnlock()
+		return nil, err
+	}
+	return sync.OnceFunc(func() {
+		if f != nil {
+			f.Close()
+		}
+		cacheLock.RUnlock()
+	}), nil
+}
+
+// lockCache takes the cache for the exclusive use of this process,
+// for deleting files, returning a function that releases it.
+// If sessions are using the cache, lockCache waits for them to finish
+// if wait is true and otherwise returns errLocked.
+func lockCache(wait bool) (release func(), err error) {
+	path, err := cacheLockPath()
+	if err != nil {
+		return nil, err
+	}
+	var f *os.File
+	if wait {
+		cacheLock.Lock()
+		f, err = waitLockFile(path, true)
+	} else {
+		if !cacheLock.TryLock() {
+			return nil, errLocked
+		}
+		f, err = lockFile(path)
+	}
+	if err != nil {
+		cacheLock.Unlock()
+		return nil, err
+	}
+	return func() {
+		if f != nil {
+			f.Close()
+		}
+		cacheLock.Unlock()
+	}, nil
+}
+
+// cacheLimits returns the maximum age of an unused cached file and the
+// maximum total size of the cache (0 for no limit), from $MOTECACHEAGE
+// (a duration like 24h) and $MOTECACHESIZE (a size like 10G).
+// Malformed settings are reported and ignored.
+func cacheLimits() (maxAge time.Duration, maxSize int64) {
+	maxAge = cacheMaxAge
+	if s := os.Getenv("MOTECACHEAGE"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d <= 0 {
+			log.Printf("ignoring malformed $MOTECACHEAGE=%s", s)
+		} else {
+			maxAge = d
+		}
+	}
+	if s := os.Getenv("MOTECACHESIZE"); s != "" {
+		n, err := parseSize(s)
+		if err != nil {
+			log.Printf("ignoring malformed $MOTECACHESIZE=%s", s)
+		} else {
+			maxSize = n
+		}
+	}
+	return maxAge, maxSize
+}
+
+// parseSize parses a size in bytes, with an optional suffix
+// K, M, G, or T for the powers of 1024.
+func parseSize(s string) (int64, error) {
+	shift := 0
+	if i := strings.IndexAny("KMGT", strings.ToUpper(s[len(s)-1:])); i >= 0 {
+		shift = 10 * (i + 1)
+		s = s[:len(s)-1]
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 || n > math.MaxInt64>>shift {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return n << shift, nil
+}
+
+// A cacheEntry is a file in the cache.
+type cacheEntry struct {
+	path string
+	size int64
+	used time.Time // modification time, which inCache updates
+	tmp  bool      // an upload in progress (or abandoned)
+}
+
+// cacheEntries returns the files in the cache.
+func cacheEntries() ([]cacheEntry, error) {
+	dir, err := cacheDir()
+	if err != nil {
+		return nil, err
+	}
+	var entries []cacheEntry
+	shards, _ := os.ReadDir(dir)
+	for _, shard := range shards {
+		if !shard.IsDir() || shard.Name() == busyName {
+			continue
+		}
+		files, _ := os.ReadDir(filepath.Join(dir, shard.Name()))
+		for _, f := range files {
+			info, err := f.Info()
+			if err != nil || !info.Mode().IsRegular() {
+				continue
+			}
+			entries = append(entries, cacheEntry{
+				path: filepath.Join(dir, shard.Name(), f.Name()),
+				size: info.Size(),
+				used: info.ModTime(),
+				tmp:  strings.HasPrefix(f.Name(), "tmp-"),
+			})
+		}
+	}
+	return entries, nil
+}
+
+// cacheEvictions returns the entries to delete, least recently used
+// first, to leave no entry unused for longer than maxAge (at the time
+// now) and the rest totalling at most maxSize bytes. A negative maxAge
+// evicts every entry; a zero maxSize sets no limit on size.
+// An upload in progress counts toward the size but is only ever
+// evicted by age, once it is clearly abandoned.
+func cacheEvictions(entries []cacheEntry, now time.Time, maxAge time.Duration, maxSize int64) []cacheEntry {
+	entries = slices.Clone(entries)
+	slices.SortFunc(entries, func(x, y cacheEntry) int { return x.used.Compare(y.used) })
+	var evict []cacheEntry
+	var total int64
+	for _, e := range entries {
+		total += e.size
+	}
+	cutoff := now.Add(-maxAge)
+	for _, e := range entries {
+		if maxAge < 0 || e.used.Before(cutoff) || maxSize > 0 && total > maxSize && !e.tmp {
+			evict = append(evict, e)
+			total -= e.size
+		}
+	}
+	return evict
+}
+
+// validHash reports whether hash is a well-formed lowercase hex SHA-256,
+// safe to use as a file name in the cache.
+func validHash(hash string) bool {
+	if len(hash) != 2*sha256.Size {
+		return false
+	}
+	for i := 0; i < len(hash); i++ {
+		c := hash[i]
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// cacheFile returns the name of the cache file for the given hash.
+func cacheFile(hash string) (string, error) {
+	dir, err := cacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, hash[:2], hash), nil
+}
+
+// inCache reports whether the file with the given hash and size
+// is already in the cache, marking it recently used if so.
+func inCache(hash string, size int64) bool {
+	if !cached(hash, size) {
+		serverMetrics.cacheMisses.Add(1)
+		return false
+	}
+	serverMetrics.cacheHits.Add(1)
+	return true
+}
+
+// cached is inCache without the counting in serverMetrics,
+// for a file that has just been uploaded.
+func cached(hash string, size int64) bool {
+	file, err := cacheFile(hash)
+	if err != nil {
+		return false
+	}
+	info, err := os.Stat(file)
+	if err != nil || info.Size() != size {
+		return false
+	}
+	now := time.Now()
+	os.Chtimes(file, now, now)
+	return true
+}
+
+// saveToCache reads size bytes from r and saves them in the cache,
+// verifying that they have the given hash.
+func saveToCache(hash string, size int64, r io.Reader) error {
+	file, err := cacheFile(hash)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o777); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(file), "tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(tmp, h), io.LimitReader(r, size))
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	if n != size {
+		tmp.Close()
+		return fmt.Errorf("upload of %s: short read: %d bytes, want %d", hash, n, size)
+	}
+	if sum := hex.EncodeToString(h.Sum(nil)); sum != hash {
+		tmp.Close()
+		return fmt.Errorf("upload of %s: content has hash %s", hash, sum)
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	err = os.Rename(tmp.Name(), file)
+	if os.IsNotExist(err) {
+		// A cleanCache took the upload for abandoned and deleted it.
+		// The caller, finding the file missing, will ask for it again.
+		return nil
+	}
+	return err
+}
+
+// The ways copyFromCache can place a cached file in a session's tree,
+// as named by the Link field of a Setup request.
+const (
+	linkClone = "clone" // a copy-on-write clone, or else a copy (the default)
+	linkCopy  = "copy"  // always a copy
+	linkHard  = "hard"  // a clone, or else a read-only hard link, or else a copy
+)
+
+// validLink reports whether link names a way to place cached files.
+func validLink(link string) bool {
+	return link == "" || link == linkClone || link == linkCopy || link == linkHard
+}
+
+// copyFromCache places the cache file with the given hash at dst,
+// executable, in the way named by link ("" means linkClone).
+//
+// Whatever the command does to its files must not corrupt the cache.
+// A copy is always safe, but copying large binaries and testdata for
+// every command is slow. A copy-on-write clone is as safe as a copy
+// and costs almost nothing, where the file system supports it. A hard
+// link costs nothing anywhere, but it is the cache file itself, so it
+// is only used when the client promises the command will not write to
+// its files, and the file is made read-only in case it does. The
+// superuser can write to read-only files, so it gets no hard links,
+// and neither does Windows, which cannot remove read-only files.
+func copyFromCache(hash, dst, link string) error {
+	file, err := cacheFile(hash)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o777); err != nil {
+		return err
+	}
+	// Never write through a link left at dst by an earlier file.
+	os.Remove(dst)
+	if link != linkCopy && cloneFile(file, dst) == nil {
+		return nil
+	}
+	if link == linkHard && runtime.GOOS != "windows" && os.Geteuid() != 0 {
+		if os.Chmod(file, 0o555) == nil && os.Link(file, dst) == nil {
+			return nil
+		}
+	}
+	src, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	f, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o777)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, src); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// remotePath converts the client's absolute slash-separated path
+// into a path in the server's temporary directory tree, stripping
+// any leading Windows volume name and rejecting paths that would
+// escape the tree.
+func remotePath(tmpdir, slashPath string) (string, error) {
+	p := slashPath
+	if len(p) >= 2 && p[1] == ':' { // Windows volume like C:
+		p = p[2:]
+	}
+	if strings.Contains(p, `\`) || slices.Contains(strings.Split(p, "/"), "..") {
+		return "", fmt.Errorf("invalid path %#q", slashPath)
+	}
+	p = path.Clean("/" + p) // now absolute
+	return filepath.Join(tmpdir, filepath.FromSlash(p)), nil
+}
+
+// mapPathEnv maps the Setup PathEnv entries, environment variables
+// whose values are client paths, into the server's temporary directory
+// tree, as remotePath maps files. A value may also be a file:// URL
+// naming a client path, which becomes a URL naming the mapped path.
+func mapPathEnv(tmpdir string, pathEnv []string) ([]string, error) {
+	var env []string
+	for _, kv := range pathEnv {
+		name, val, ok := strings.Cut(kv, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid path environment variable %#q", kv)
+		}
+		val, isURL := strings.CutPrefix(val, "file://")
+		p, err := remotePath(tmpdir, val)
+		if err != nil {
+			return nil, err
+		}
+		if isURL {
+			p = fileURL(p)
+		}
+		env = append(env, name+"="+p)
+	}
+	return env, nil
+}
+
+// fileURL returns the file:// URL for the absolute path p.
+func fileURL(p string) string {
+	p = filepath.ToSlash(p)
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p // a Windows path like C:/x is file:///C:/x
+	}
+	return "file://" + p
+}
+
+// clientPath returns the client's path for the command name, which is
+// how the uploaded files are named: an absolute path (possibly with a
+// Windows volume) is one already, and a relative path is relative to
+// the client's directory dir. It returns "" for a name that is not a
+// path at all, which the client did not upload and the server looks up
+// on its own PATH.
+func clientPath(dir, name string) string {
+	p := strings.ReplaceAll(name, `\`, "/") // a Windows client sends native paths in Args
+	if strings.HasPrefix(p, "/") || len(p) >= 2 && p[1] == ':' {
+		return p
+	}
+	if !strings.Contains(p, "/") {
+		return ""
+	}
+	return path.Join(dir, p)
+}
+
+// exeName returns the name to store the uploaded file dst under on a
+// server running goos, given that its contents are in the file src.
+//
+// On Windows, an executable that arrives without the .exe suffix is
+// given one. Windows decides what a file is by its name, so os/exec
+// cannot run one that is named anything else, and a Go binary
+// cross-compiled for Windows often arrives this way: "go build -o
+// prog" names the file prog whatever system it is built for.
+// Everywhere else the name is left alone.
+func exeName(goos, dst, src string) string {
+	if goos != "windows" || strings.EqualFold(filepath.Ext(dst), ".exe") {
+		return dst
+	}
+	f, err := pe.Open(src)
+	if err != nil {
+		return dst // not a Windows binary at all
+	}
+	defer f.Close()
+	if f.Characteristics&pe.IMAGE_FILE_DLL != 0 {
+		// A library, not a program: it is loaded by name, so renaming
+		// it would keep whatever loads it from finding it.
+		return dst
+	}
+	return dst + ".exe"
+}
@@ -0,0 +1,388 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mote
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"debug/pe"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestExeName checks the .exe suffix that a Windows server adds to the
+// executables it is sent. The server it is testing is a Windows one
+// whatever system runs the test, so exeName takes the GOOS as an
+// argument rather than reading runtime.GOOS.
+func TestExeName(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("no go command")
+	}
+	dir := t.TempDir()
+	src := filepath.Join(dir, "hello.go")
+	if err := os.WriteFile(src, []byte("package main\n\nfunc main() { println(\"hi\") }\n"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	build := func(goos string) string {
+		bin := filepath.Join(dir, "hello-"+goos)
+		c := exec.Command("go", "build", "-o", bin, src)
+		c.Env = append(os.Environ(), "GOOS="+goos, "GOARCH=amd64", "CGO_ENABLED=0", "GOFLAGS=", "GO111MODULE=off")
+		if out, err := c.CombinedOutput(); err != nil {
+			t.Fatalf("go build %s: %v\n%s", goos, err, out)
+		}
+		return bin
+	}
+	windows := build("windows")
+	linux := build("linux")
+	library := markDLL(t, windows, filepath.Join(dir, "hello-dll"))
+	text := filepath.Join(dir, "text")
+	if err := os.WriteFile(text, []byte("not a binary\n"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		goos string
+		dst  string
+		src  string
+		want string
+	}{
+		{"windows", "prog", windows, "prog.exe"},
+		// A test binary's name ends in .test, which Windows does not
+		// consider executable either.
+		{"windows", "strings.test", windows, "strings.test.exe"},
+		{"windows", "prog.exe", windows, "prog.exe"},
+		{"windows", "PROG.EXE", windows, "PROG.EXE"},
+		// Only programs: a library is loaded by name, and anything that
+		// is not a Windows binary is not a program to be run.
+		{"windows", "hello.dll", library, "hello.dll"},
+		{"windows", "testdata/input", text, "testdata/input"},
+		{"windows", "prog", linux, "prog"},
+		// Every other system runs a file under the name it arrived with.
+		{"linux", "prog", windows, "prog"},
+		{"darwin", "prog", linux, "prog"},
+	}
+	for _, tt := range tests {
+		if got := exeName(tt.goos, tt.dst, tt.src); got != tt.want {
+			t.Errorf("exeName(%s, %q, %s) = %q, want %q",
+				tt.goos, tt.dst, filepath.Base(tt.src), got, tt.want)
+		}
+	}
+}
+
+// TestClientPath checks which command names name an uploaded file.
+func TestClientPath(t *testing.T) {
+	tests := []struct{ dir, name, want string }{
+		{"/home/rsc/mypkg", "/tmp/go-build/b001/mypkg.test", "/tmp/go-build/b001/mypkg.test"},
+		{"/home/rsc/mypkg", "./mypkg.test", "/home/rsc/mypkg/mypkg.test"},
+		{"/home/rsc/mypkg", "../testprog", "/home/rsc/testprog"},
+		// A Windows client sends native paths, absolute ones with a volume.
+		{"C:/Users/rsc/mypkg", `C:\Users\rsc\mypkg\mypkg.test`, "C:/Users/rsc/mypkg/mypkg.test"},
+		{"C:/Users/rsc/mypkg", `.\mypkg.test`, "C:/Users/rsc/mypkg/mypkg.test"},
+		// A bare command name is not a file the client uploaded: mote
+		// uploads a command only when it names a path. The server finds
+		// this one on its own PATH.
+		{"/home/rsc/mypkg", "hostname", ""},
+	}
+	for _, tt := range tests {
+		if got := clientPath(tt.dir, tt.name); got != tt.want {
+			t.Errorf("clientPath(%q, %q) = %q, want %q", tt.dir, tt.name, got, tt.want)
+		}
+	}
+}
+
+// markDLL copies the Windows binary src to dst, setting the flag that
+// marks it a library rather than a program, and returns dst.
+func markDLL(t *testing.T, src, dst string) string {
+	t.Helper()
+	data, err := os.ReadFile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Characteristics is the last field of the 20-byte COFF header,
+	// which follows the "PE\0\0" signature at the offset named at 0x3c.
+	off := int(binary.LittleEndian.Uint32(data[0x3c:])) + 4 + 18
+	binary.LittleEndian.PutUint16(data[off:], binary.LittleEndian.Uint16(data[off:])|pe.IMAGE_FILE_DLL)
+	if err := os.WriteFile(dst, data, 0o666); err != nil {
+		t.Fatal(err)
+	}
+	return dst
+}
+
+func TestCacheEvictions(t *testing.T) {
+	now := time.Now()
+	entry := func(name string, size int64, age time.Duration) cacheEntry {
+		return cacheEntry{path: name, size: size, used: now.Add(-age), tmp: strings.HasPrefix(name, "tmp-")}
+	}
+	entries := []cacheEntry{
+		entry("new", 10, time.Minute),
+		entry("old", 10, 5*time.Hour),
+		entry("mid", 10, time.Hour),
+		entry("tmp-upload", 10, 2*time.Hour),
+		entry("recent", 10, 10*time.Minute),
+	}
+	tests := []struct {
+		maxAge  time.Duration
+		maxSize int64
+		want    string
+	}{
+		{3 * time.Hour, 0, "old"},
+		{30 * time.Minute, 0, "old tmp-upload mid"},
+		{-1, 0, "old tmp-upload mid recent new"},
+		// Least recently used go first, but an upload in progress
+		// is only ever deleted by age.
+		{3 * time.Hour, 30, "old mid"},
+		{3 * time.Hour, 15, "old mid recent new"},
+		{3 * time.Hour, 50, "old"},
+		{24 * time.Hour, 50, ""},
+	}
+	for _, tt := range tests {
+		var names []string
+		for _, e := range cacheEvictions(entries, now, tt.maxAge, tt.maxSize) {
+			names = append(names, e.path)
+		}
+		if got := strings.Join(names, " "); got != tt.want {
+			t.Errorf("cacheEvictions(maxAge=%v, maxSize=%d) = %q, want %q", tt.maxAge, tt.maxSize, got, tt.want)
+		}
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int64
+		ok   bool
+	}{
+		{"0", 0, true},
+		{"12345", 12345, true},
+		{"10K", 10 << 10, true},
+		{"3M", 3 << 20, true},
+		{"2G", 2 << 30, true},
+		{"2g", 2 << 30, true},
+		{"1T", 1 << 40, true},
+		{"G", 0, false},
+		{"-1", 0, false},
+		{"1.5G", 0, false},
+		{"10X", 0, false},
+		{"9999999999T", 0, false},
+	}
+	for _, tt := range tests {
+		got, err := parseSize(tt.s)
+		if got != tt.want || (err == nil) != tt.ok {
+			t.Errorf("parseSize(%q) = %d, %v; want %d, ok=%v", tt.s, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestCleanCacheQuota(t *testing.T) {
+	setupDirs(t)
+	t.Setenv("MOTECACHESIZE", "25")
+	now := time.Now()
+	var files []string
+	for i, c := range "abc" {
+		file, err := cacheFile(strings.Repeat(string(c), 64))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.MkdirAll(filepath.Dir(file), 0o777); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, make([]byte, 10), 0o666); err != nil {
+			t.Fatal(err)
+		}
+		used := now.Add(-time.Duration(3-i) * time.Minute) // a is least recently used
+		os.Chtimes(file, used, used)
+		files = append(files, file)
+	}
+	cleanCache()
+	for i, file := range files {
+		_, err := os.Stat(file)
+		if kept := err == nil; kept != (i > 0) {
+			t.Errorf("%s: kept=%v, want %v", filepath.Base(file)[:1], kept, i > 0)
+		}
+	}
+}
+
+// TestCleanCacheDuringUpload checks that a session waiting for its
+// client's upload does not keep cleanCache from enforcing the quota.
+func TestCleanCacheDuringUpload(t *testing.T) {
+	setupDirs(t)
+	t.Setenv("MOTECACHESIZE", "1")
+	stale, err := cacheFile(strings.Repeat("a", 64))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(stale), 0o777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(stale, make([]byte, 10), 0o666); err != nil {
+		t.Fatal(err)
+	}
+
+	content := "#!/bin/sh\necho uploaded\n"
+	hash := fmt.Sprintf("%x", sha256.Sum256([]byte(content)))
+	conn := startServeClient(t, "")
+	err = conn.writePacket(&Request{
+		Type:  "Setup",
+		Args:  []string{"./x.sh"},
+		Dir:   "/mote-test",
+		Files: []*File{{Path: "/mote-test/x.sh", Hash: hash, Size: int64(len(content))}},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var resp Response
+	if _, err := conn.readPacket(&resp); err != nil || resp.Type != "Need" {
+		t.Fatalf("got %+v, %v; want Need", resp, err)
+	}
+	cleanCache()
+	if _, err := os.Stat(stale); err == nil {
+		t.Errorf("cleanCache left the cache over quota during an upload")
+	}
+	err = conn.writePacketStream(&Request{Type: "Upload"}, int64(len(content)), strings.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.readPacket(&resp); err != nil || resp.Type != "Ready" {
+		t.Fatalf("got %+v, %v; want Ready", resp, err)
+	}
+}
+
+// TestCleanCacheRace races sessions using the same cached file against
+// cleanCache, set up to delete every file it finds. Without the hold
+// the sessions take on the cache, the cleaner deletes the file between
+// one session's finding it and copying it out.
+func TestCleanCacheRace(t *testing.T) {
+	setupDirs(t)
+	t.Setenv("MOTECACHEAGE", "1ns")
+	dir := t.TempDir()
+	script := filepath.Join(dir, "x.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\necho raced\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	var files []*File
+	if err := addFile(&files, script); err != nil {
+		t.Fatal(err)
+	}
+
+	stop := make(chan struct{})
+	cleaned := make(chan struct{})
+	go func() {
+		defer close(cleaned)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				cleanCache()
+			}
+		}
+	}()
+	defer func() {
+		close(stop)
+		<-cleaned
+	}()
+
+	run := func() error {
+		cconn, sconn := net.Pipe()
+		defer cconn.Close()
+		go func() {
+			serve(sconn, "", nil)
+			sconn.Close()
+		}()
+		conn, err := clientConn(cconn, "")
+		if err != nil {
+			return err
+		}
+		var outb, errb bytes.Buffer
+		w, err := conn.Run(t.Context(), &Exec{Args: []string{"./x.sh"}, Dir: filepath.ToSlash(dir), Files: files, Stdout: &outb, Stderr: &errb})
+		if err != nil {
+			return err
+		}
+		if w.Code != 0 || outb.String() != "raced\n" {
+			return fmt.Errorf("code=%d stdout=%q stderr=%q, want 0, %q", w.Code, outb.String(), errb.String(), "raced\n")
+		}
+		return nil
+	}
+	for range 100 {
+		errc := make(chan error, 2)
+		for range 2 {
+			go func() { errc <- run() }()
+		}
+		for range 2 {
+			if err := <-errc; err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+// TestCopyFromCache checks each way of placing a cached file: the
+// result is executable and has the cached content, and only a hard
+// link, where one is made, shares the cache file.
+func TestCopyFromCache(t *testing.T) {
+	for _, link := range []string{linkCopy, linkClone, linkHard} {
+		t.Run(link, func(t *testing.T) {
+			setupDirs(t)
+			hash := strings.Repeat("a", 64)
+			file, err := cacheFile(hash)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.MkdirAll(filepath.Dir(file), 0o777); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(file, []byte("cached"), 0o666); err != nil {
+				t.Fatal(err)
+			}
+			dst := filepath.Join(t.TempDir(), "sub", "x")
+			// A stale file at dst is replaced, not written through.
+			if err := os.MkdirAll(filepath.Dir(dst), 0o777); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(dst, []byte("stale file"), 0o666); err != nil {
+				t.Fatal(err)
+			}
+			if err := copyFromCache(hash, dst, link); err != nil {
+				t.Fatal(err)
+			}
+			data, err := os.ReadFile(dst)
+			if err != nil || string(data) != "cached" {
+				t.Fatalf("ReadFile(dst) = %q, %v, want %q", data, err, "cached")
+			}
+			info, err := os.Stat(dst)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if runtime.GOOS != "windows" && info.Mode()&0o111 == 0 {
+				t.Errorf("dst mode %v is not executable", info.Mode())
+			}
+			cacheInfo, err := os.Stat(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			linked := os.SameFile(info, cacheInfo)
+			if linked != (link == linkHard && runtime.GOOS != "windows" && os.Geteuid() != 0) {
+				t.Errorf("dst linked to cache = %v", linked)
+			}
+			if linked {
+				return
+			}
+			if err := os.WriteFile(dst, []byte("changed"), 0o666); err != nil {
+				t.Fatal(err)
+			}
+			if data, _ := os.ReadFile(file); string(data) != "cached" {
+				t.Errorf("writing dst changed cache file to %q", data)
+			}
+		})
+	}
+}
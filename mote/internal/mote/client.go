@@ -0,0 +1,541 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// cmdRun implements the default mote command: run cmd on a server.
+func cmdRun(args []string) {
+	server := ""
+	if strings.HasPrefix(args[0], "@") {
+		server, args = args[0][1:], args[1:]
+		if len(args) == 0 {
+			usage()
+		}
+	}
+	// Resolve the command to the file it names before anything else
+	// looks at it: the name travels to the server in Args, which is how
+	// the server knows which uploaded file to run.
+	if !validLink(*link) {
+		log.Fatalf("-link must be copy, clone, or hard")
+	}
+	args[0] = cmdFile(args[0])
+	list, err := resolveAliases(server, args[0])
+	if err != nil {
+		log.Fatal(err)
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		log.Fatal(err)
+	}
+	// For a group, which server runs the command, and so which
+	// alias settings apply, is only known once a server is chosen.
+	conn, a, url, err := dialAliases(list)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer conn.Close()
+	root, goTest := isGoTest(args, dir)
+	if *watch {
+		if goTest {
+			log.Fatalf("-watch does not apply to go test from source")
+		}
+		watchRun(conn, a, url, args, dir)
+		return
+	}
+	var e *Exec
+	if goTest {
+		// See gotest.go.
+		if e, err = goTestExec(conn, args, dir, root, a); err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		if isFileCmd(args[0]) {
+			if err := checkBinary(conn, args[0]); err != nil {
+				log.Fatal(err)
+			}
+		}
+		files, err := uploadList(args[0], uploads, *testData, a.Exclude)
+		if err != nil {
+			log.Fatal(err)
+		}
+		e = runExec(args, dir, files, a)
+	}
+
+	// The first interrupt kills the remote command (or stops the
+	// upload); a second one gives up on it.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if a.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(a.Timeout))
+		defer cancel()
+	}
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	defer signal.Stop(sig)
+	go func() {
+		<-sig
+		cancel()
+		<-sig
+		os.Exit(1)
+	}()
+
+	w, err := conn.Run(ctx, e)
+	if errors.Is(err, context.DeadlineExceeded) {
+		log.Fatalf("timed out after %v", time.Duration(a.Timeout))
+	}
+	if err != nil {
+		log.Fatal(conn.abort(err))
+	}
+	reportWait(w)
+	if conn.GOOS != "" && conn.GOARCH != "" && !a.guessed {
+		name := conn.GOOS + "-" + conn.GOARCH
+		// A group of that name counts as an alias for it.
+		if cfg, err := readConfig(); err == nil && len(cfg.lookup(name)) == 0 {
+			setAlias(name, url)
+		}
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		log.Fatalf("remote command timed out after %v: %s", time.Duration(a.Timeout), w.Status)
+	}
+	if w.Code < 0 {
+		log.Fatalf("remote command killed: %s", w.Status)
+	}
+	conn.Close()
+	os.Exit(w.Code)
+}
+
+// runExec returns the Exec for running args, from the client directory
+// dir, on a server with alias settings a, as the mote flags say.
+func runExec(args []string, dir string, files []*File, a *alias) *Exec {
+	return &Exec{
+		Args:      args,
+		Dir:       filepath.ToSlash(dir),
+		Files:     files,
+		Env:       a.Env,
+		Exclusive: *exclusive,
+		Link:      *link,
+		Forwards:  forwards,
+		CoreDir:   *coreDir,
+		Stdout:    os.Stdout,
+		Stderr:    os.Stderr,
+	}
+}
+
+// reportWait prints what the mote flags ask to hear about a command
+// that has finished.
+func reportWait(w *Wait) {
+	if *exclusive {
+		log.Printf("waited %v for exclusive use of server", w.Waited.Round(time.Millisecond))
+	}
+	if *stats {
+		if w.Usage == nil {
+			log.Printf("server does not report resource usage")
+		} else {
+			log.Printf("%v", w.Usage)
+		}
+	}
+	if *coreDir != "" && w.Code < 0 {
+		if w.Core == "" {
+			log.Printf("no core dump found on server")
+		} else {
+			log.Printf("saved core dump in %s", w.Core)
+		}
+	}
+}
+
+// goosGoarchRE matches a plausible $GOOS-$GOARCH pair like linux-amd64.
+var goosGoarchRE = regexp.MustCompile(`^[a-z0-9]+-[a-z0-9]+$`)
+
+// resolveServer resolves the @name argument (possibly empty) to a
+// server URL: for a group, the URL of its first server.
+// See resolveAliases.
+func resolveServer(name, cmdName string) (string, error) {
+	list, err := resolveAliases(name, cmdName)
+	if err != nil {
+		return "", err
+	}
+	return list[0].URL, nil
+}
+
+// resolveAliases resolves the @name argument (possibly empty) to the
+// aliases for the servers it names: a single server, or every server
+// in a group (see config.lookup). A URL is an alias with no settings.
+// An empty name falls back to $MOTE, then $GOOS-$GOARCH from the
+// environment, then the GOOS-GOARCH of the binary being uploaded.
+// A GOOS-GOARCH name with no alias means a gomote, if gomote is
+// installed, and otherwise, for linux on another architecture, local
+// emulation, if it is possible. Emulation is only a stand-in for a
+// real server, so its alias is marked as guessed, not to be saved.
+func resolveAliases(name, cmdName string) ([]*alias, error) {
+	if name == "" {
+		switch {
+		case os.Getenv("MOTE") != "":
+			name = os.Getenv("MOTE")
+		case os.Getenv("GOOS") != "" && os.Getenv("GOARCH") != "":
+			name = os.Getenv("GOOS") + "-" + os.Getenv("GOARCH")
+		case isFileCmd(cmdName):
+			goos, goarch, err := binaryOSArch(cmdName)
+			if err != nil {
+				return nil, fmt.Errorf("cannot choose server: %v", err)
+			}
+			name = goos + "-" + goarch
+		default:
+			return nil, fmt.Errorf("no server specified (set $MOTE or use @server)")
+		}
+	}
+	if strings.Contains(name, "://") {
+		return []*alias{{URL: name}}, nil
+	}
+	cfg, err := readConfig()
+	if err != nil {
+		return nil, err
+	}
+	if list := cfg.lookup(name); len(list) > 0 {
+		return list, nil
+	}
+	if goosGoarchRE.MatchString(name) {
+		goos, goarch, _ := strings.Cut(name, "-")
+		if _, err := exec.LookPath("gomote"); err == nil {
+			builder, err := gomoteBuilder(goos, goarch)
+			if err != nil {
+				return nil, err
+			}
+			return []*alias{{URL: "gomote://" + builder}}, nil
+		}
+		if qemuFallback(goos, goarch) {
+			return []*alias{{URL: "qemu://" + name, guessed: true}}, nil
+		}
+	}
+	return nil, fmt.Errorf("no alias for %s", name)
+}
+
+// An Exec describes a command to run on a server.
+type Exec struct {
+	Args   []string  // command arguments; Args[0] is the command itself
+	Dir    string    // client working directory, in slash form
+	Files  []*File   // files to place on the server
+	Env    []string  // extra environment variables
+	Stdin  io.Reader // source of standard input (nil means none)
+	Stdout io.Writer // destination for standard output (nil means discard)
+	Stderr io.Writer // destination for standard error (nil means discard)
+
+	// Exclusive asks the server to run the command with no others
+	// running: it waits for running commands to finish and holds off
+	// new ones until this one exits.
+	Exclusive bool
+
+	// Link says how the server places the files in the command's
+	// directory tree: "copy", "clone" (the default), or "hard".
+	// See copyFromCache.
+	Link string
+
+	// Forwards lists ports to forward between the client and the
+	// server while the command runs. See forward.go.
+	Forwards []*Forward
+
+	// CoreDir, if set, asks the server to collect a core dump if the
+	// command crashes, and names the client directory in which to save
+	// it and the binary that crashed. See core.go.
+	CoreDir string
+
+	// PathEnv holds environment variables whose values are client
+	// paths, or file:// URLs naming them, which the server maps into
+	// its copy of the tree, like Dir. See mapPathEnv.
+	PathEnv []string
+}
+
+// A Wait describes how a command finished.
+type Wait struct {
+	Code   int           // exit code (negative if killed by a signal)
+	Status string        // os.ProcessState description of the exit
+	Waited time.Duration // time spent waiting for exclusive use
+	Usage  *Usage        // resources used, or nil if the server does not report them
+	Core   string        // path of the saved core dump, if any (see Exec.CoreDir)
+}
+
+// A Usage describes the resources a command used on the server.
+type Usage struct {
+	Wall   time.Duration // time the command ran
+	User   time.Duration // user CPU time
+	System time.Duration // system CPU time
+	MaxRSS int64         // maximum resident set size in bytes (0 if unknown)
+	Output int64         // bytes of standard output and standard error
+}
+
+func (u *Usage) String() string {
+	rss := "unknown"
+	if u.MaxRSS > 0 {
+		rss = fmt.Sprintf("%.1f MB", float64(u.MaxRSS)/1e6)
+	}
+	return fmt.Sprintf("wall %v, user %v, system %v, max RSS %s, output %d bytes",
+		u.Wall.Round(time.Millisecond), u.User.Round(time.Millisecond), u.System.Round(time.Millisecond), rss, u.Output)
+}
+
+// Run runs the command described by e on the server at the
+// other end of c: setup, upload, start, output streaming, exit status.
+// A server runs one command per connection.
+//
+// If ctx is done before the command starts, Run stops and returns
+// ctx.Err(); an upload in progress is abandoned, leaving c unusable.
+// If ctx is done while the command runs, Run kills it and returns the
+// Wait reporting its end.
+//
+// If the server is too old to support a feature that e asks for,
+// Run writes a warning to e.Stderr and runs the command without it.
+func (c *Conn) Run(ctx context.Context, e *Exec) (*Wait, error) {
+	stdout, stderr := e.Stdout, e.Stderr
+	if stdout == nil {
+		stdout = io.Discard
+	}
+	if stderr == nil {
+		stderr = io.Discard
+	}
+	req := &Request{
+		Type:      "Setup",
+		Files:     e.Files,
+		Args:      e.Args,
+		Dir:       e.Dir,
+		Env:       e.Env,
+		Stdin:     e.Stdin != nil,
+		Exclusive: e.Exclusive,
+		Link:      e.Link,
+		PathEnv:   e.PathEnv,
+		Drain:     c.has(capDrain),
+	}
+	// An older server ignores Setup fields it does not know, which
+	// would silently drop the feature, or, for standard input, fail
+	// at the first Stdin request. Say what is being dropped instead.
+	stdin := e.Stdin
+	if stdin != nil && !c.has(capStdin) {
+		fmt.Fprintf(stderr, "mote: server does not support standard input; running without it\n")
+		stdin, req.Stdin = nil, false
+	}
+	if req.Exclusive && !c.has(capExclusive) {
+		fmt.Fprintf(stderr, "mote: server does not support exclusive use; running without it\n")
+		req.Exclusive = false
+	}
+	if req.Link != "" && !c.has(capLink) {
+		fmt.Fprintf(stderr, "mote: server does not support -link; copying files\n")
+		req.Link = ""
+	}
+	if len(req.PathEnv) > 0 && !c.has(capPathEnv) {
+		fmt.Fprintf(stderr, "mote: server does not support path environment variables; running without them\n")
+		req.PathEnv = nil
+	}
+	var cores *coreSaver
+	if e.CoreDir != "" {
+		if c.has(capCore) {
+			req.Core = true
+			cores = newCoreSaver(e.CoreDir, e.Args[0])
+			defer cores.close()
+		} else {
+			fmt.Fprintf(stderr, "mote: server does not support core dumps; running without them\n")
+		}
+	}
+	// Bind the client's ports now, so that a port in use stops the
+	// command before it runs; connections wait until Start.
+	fwd := newForwarder(c, false, stderr)
+	defer fwd.shutdown()
+	if len(e.Forwards) > 0 && !c.has(capForward) {
+		fmt.Fprintf(stderr, "mote: server does not support port forwarding; running without it\n")
+	} else {
+		for _, fw := range e.Forwards {
+			if fw.Remote {
+				req.Listen = append(req.Listen, fw.Listen)
+				fwd.remote[fw.Listen] = fw.Dial
+			} else if err := fwd.listen(fw.Listen, fw.Dial); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := c.writePacket(req, nil); err != nil {
+		return nil, err
+	}
+
+	// Answer Need with the upload, until the server says Ready.
+	byHash := make(map[string]*File)
+	for _, f := range e.Files {
+		byHash[f.Hash] = f
+	}
+Setup:
+	for {
+		resp, _, err := c.readResponse()
+		if err != nil {
+			return nil, err
+		}
+		switch resp.Type {
+		default:
+			return nil, fmt.Errorf("unexpected response type %q", resp.Type)
+
+		case "Need":
+			var readers []io.Reader
+			var size int64
+			for _, hash := range resp.Need {
+				f := byHash[hash]
+				if f == nil {
+					return nil, fmt.Errorf("server needs unknown hash %s", hash)
+				}
+				readers = append(readers, io.LimitReader(&lazyFile{name: filepath.FromSlash(f.Path)}, f.Size))
+				size += f.Size
+			}
+			r := &ctxReader{ctx, io.MultiReader(readers...)}
+			if err := c.writePacketStream(&Request{Type: "Upload"}, size, r); err != nil {
+				if ctx.Err() != nil {
+					return nil, ctx.Err()
+				}
+				return nil, fmt.Errorf("upload: %v", err)
+			}
+
+		case "Ready":
+			break Setup
+		}
+	}
+
+	// The command is about to run. Kill it when ctx is done.
+	// The connection's write lock keeps a Kill from interleaving with
+	// the Start packet or standard input.
+	if ctx.Err() != nil {
+		c.writePacket(&Request{Type: "Kill"}, nil)
+		return nil, ctx.Err()
+	}
+	if err := c.writePacket(&Request{Type: "Start"}, nil); err != nil {
+		return nil, err
+	}
+	stop := context.AfterFunc(ctx, func() {
+		c.writePacket(&Request{Type: "Kill"}, nil)
+	})
+	defer stop()
+	if stdin != nil {
+		go c.sendStdin(stdin)
+	}
+	fwd.start()
+
+	var waited time.Duration
+	for {
+		resp, data, err := c.readResponse()
+		if err != nil {
+			return nil, err
+		}
+		switch resp.Type {
+		default:
+			return nil, fmt.Errorf("unexpected response type %q", resp.Type)
+
+		case "Output":
+			w := stdout
+			if resp.Stderr {
+				w = stderr
+			}
+			w.Write(data)
+
+		case "Exclusive":
+			waited = resp.Waited
+
+		case "Draining":
+			fmt.Fprintf(stderr, "mote: server is shutting down; command has %v to finish\n", resp.Grace.Round(time.Second))
+
+		case "Open":
+			fwd.open(resp.Chan, resp.Addr)
+
+		case "Data":
+			fwd.data(resp.Chan, data)
+
+		case "Close":
+			fwd.close(resp.Chan, resp.Status)
+
+		case "Core":
+			if cores == nil {
+				return nil, fmt.Errorf("unexpected response type %q", resp.Type)
+			}
+			cores.write(resp.Core, data)
+
+		case "Exit":
+			w := &Wait{Code: resp.ExitCode, Status: resp.Status, Waited: waited}
+			if cores != nil {
+				// A core dump that cannot be saved is
+				// no reason to lose the command's exit.
+				core, err := cores.close()
+				if err != nil {
+					fmt.Fprintf(stderr, "mote: %v\n", err)
+				}
+				w.Core = core
+			}
+			if c.has(capStats) {
+				w.Usage = &Usage{
+					Wall:   resp.WallTime,
+					User:   resp.UserTime,
+					System: resp.SystemTime,
+					MaxRSS: resp.MaxRSS,
+					Output: resp.Output,
+				}
+			}
+			return w, nil
+		}
+	}
+}
+
+// stdinChunk is the most standard input sent in one Stdin request.
+const stdinChunk = 32 << 10
+
+// sendStdin sends r to the command as Stdin requests, until r ends or
+// the connection fails. Like an exec.Cmd reading an io.Reader that is
+// not a file, it may go on waiting for input after the command exits.
+func (c *Conn) sendStdin(r io.Reader) {
+	buf := make([]byte, stdinChunk)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			if c.writePacket(&Request{Type: "Stdin"}, buf[:n]) != nil {
+				return
+			}
+		}
+		if err != nil {
+			// Stdin with no data is the end of the input.
+			c.writePacket(&Request{Type: "Stdin"}, nil)
+			return
+		}
+	}
+}
+
+// A ctxReader is a reader that fails once ctx is done,
+// to stop an upload partway through.
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r *ctxReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
+
+// readResponse reads one response packet,
+// turning a Response with Error set into an error.
+func (c *Conn) readResponse() (*Response, []byte, error) {
+	var resp Response
+	data, err := c.readPacket(&resp)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading response: %v", err)
+	}
+	if resp.Error != "" {
+		return nil, nil, fmt.Errorf("server: %s", resp.Error)
+	}
+	return &resp, data, nil
+}
@@ -0,0 +1,33 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mote
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// cloneFile creates dst as a copy-on-write clone of src (a reflink),
+// sharing src's disk blocks until either file is written. It fails on
+// file systems that cannot clone files, such as ext4 and tmpfs, and
+// across file systems, leaving no dst behind.
+func cloneFile(src, dst string) error {
+	sf, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer sf.Close()
+	df, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o777)
+	if err != nil {
+		return err
+	}
+	if err := unix.IoctlFileClone(int(df.Fd()), int(sf.Fd())); err != nil {
+		df.Close()
+		os.Remove(dst)
+		return err
+	}
+	return df.Close()
+}
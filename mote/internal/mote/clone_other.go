@@ -0,0 +1,13 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !linux
+
+package mote
+
+// cloneFile reports that this system has no way to clone files that
+// mote knows how to use. Callers fall back to copying.
+func cloneFile(src, dst string) error {
+	return errUnsupported
+}
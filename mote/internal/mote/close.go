@@ -2,7 +2,7 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-package main
+package mote
 
 import (
 	"errors"
@@ -36,10 +36,13 @@ func cmdClose(args []string) {
 		}
 		name = "tail://" + reg
 	}
-	rawURL, err := resolveServer(name, "", nil)
+	rawURL, err := resolveServer(name, "")
 	if err != nil {
 		log.Fatal(err)
 	}
+	if isExecURL(rawURL) {
+		log.Fatalf("nothing to close for %s", rawURL)
+	}
 	u, err := url.Parse(rawURL)
 	if err != nil {
 		log.Fatalf("invalid server URL %s: %v", rawURL, err)
@@ -47,9 +50,9 @@ func cmdClose(args []string) {
 	switch u.Scheme {
 	default:
 		err = fmt.Errorf("unknown server URL scheme %s://", u.Scheme)
-	case "tcp":
+	case "tcp", "qemu", "unix", "local", "ws", "wss":
 		err = fmt.Errorf("nothing to close for %s", rawURL)
-	case "ssh":
+	case "ssh", "ssh+hex":
 		err = closeSSH(u)
 	case "tail":
 		err = daemonStop(u.Host)
@@ -75,7 +78,11 @@ func closeAll() error {
 			}
 		}
 	}
-	for _, name := range tailNames() {
+	names, err := tailNames()
+	if err != nil {
+		errs = append(errs, err)
+	}
+	for _, name := range names {
 		if err := daemonStop(name); err != nil {
 			errs = append(errs, err)
 		}
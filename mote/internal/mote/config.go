@@ -0,0 +1,470 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mote
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"maps"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// configDir returns the mote configuration directory,
+// creating it if necessary.
+// $MOTECONFIG overrides the default, for testing.
+func configDir() (string, error) {
+	dir := os.Getenv("MOTECONFIG")
+	if dir == "" {
+		base, err := os.UserConfigDir()
+		if err != nil {
+			return "", fmt.Errorf("finding config directory: %v", err)
+		}
+		dir = filepath.Join(base, "mote")
+	}
+	if err := os.MkdirAll(dir, 0o777); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// cacheDir returns the mote server's content-addressed cache directory,
+// creating it if necessary.
+// $MOTECACHE overrides the default, for testing.
+func cacheDir() (string, error) {
+	dir := os.Getenv("MOTECACHE")
+	if dir == "" {
+		base, err := os.UserCacheDir()
+		if err != nil {
+			return "", fmt.Errorf("finding cache directory: %v", err)
+		}
+		dir = filepath.Join(base, "mote", "cache")
+	}
+	if err := os.MkdirAll(dir, 0o777); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// cmdLogin implements "mote login URL", establishing the credentials
+// that a transport needs before it can be used: Tailscale credentials
+// for tail://name, or the password for tcp://host:port.
+func cmdLogin(args []string) {
+	if len(args) != 1 {
+		usage()
+	}
+	const form = "login URL must have the form tail://name, tcp://host:port, or ws[s]://host[:port]/path"
+	u, err := url.Parse(args[0])
+	if err != nil {
+		log.Fatalf("%s", form)
+	}
+	switch u.Scheme {
+	default:
+		log.Fatalf("%s", form)
+	case "tail":
+		if u.Host == "" {
+			log.Fatalf("%s", form)
+		}
+		if err := tailLogin(u.Host); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("logged in as mote-%s", u.Host)
+	case "tcp":
+		if err := checkTCPURL(u); err != nil {
+			log.Fatal(err)
+		}
+		if u.Port() == "" {
+			log.Fatalf("%s", form)
+		}
+		loginPassword(tcpKey(u))
+	case "ws", "wss":
+		if err := checkWSURL(u); err != nil {
+			log.Fatal(err)
+		}
+		loginPassword(wsKey(u))
+	}
+}
+
+// loginPassword prompts for the password for key and records it.
+func loginPassword(key string) {
+	password, err := promptPassword(key)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := setPassword(key, password); err != nil {
+		log.Fatal(err)
+	}
+	file, err := passwordFile()
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("wrote password for %s to %s", key, file)
+}
+
+func passwordFile() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "password.txt"), nil
+}
+
+// promptPassword prompts for the password to share with the server
+// named by key, without echoing it when standard input is a terminal.
+func promptPassword(key string) (string, error) {
+	fmt.Fprintf(os.Stderr, "password for %s: ", key)
+	line, err := readLine()
+	if err != nil {
+		return "", err
+	}
+	password := strings.TrimSpace(line)
+	if password == "" {
+		return "", fmt.Errorf("no password provided")
+	}
+	return password, nil
+}
+
+// readLine reads one line from standard input, suppressing the echo
+// of a terminal so that a password does not appear on the screen.
+func readLine() (string, error) {
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		line, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Fprintf(os.Stderr, "\n") // ReadPassword swallowed the typed newline
+		return string(line), err
+	}
+	sc := bufio.NewScanner(os.Stdin)
+	if !sc.Scan() {
+		return "", sc.Err()
+	}
+	return sc.Text(), nil
+}
+
+// readPasswords reads the password definitions from password.txt,
+// keyed by server URL.
+func readPasswords() (map[string]string, error) {
+	passwords := make(map[string]string)
+	file, err := passwordFile()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return passwords, nil
+		}
+		return nil, err
+	}
+	lineno := 0
+	for line := range strings.Lines(string(data)) {
+		lineno++
+		line = strings.TrimRight(line, "\r\n")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		// Split at the first space or tab instead of using Fields:
+		// everything after it is the password, spaces and all.
+		// The error mentions no part of the line: whatever is malformed
+		// about it, it is a line from the password file.
+		i := strings.IndexAny(line, " \t")
+		if i < 0 {
+			return nil, fmt.Errorf("%s:%d: malformed line", file, lineno)
+		}
+		key, password := line[:i], strings.TrimLeft(line[i:], " \t")
+		if password == "" {
+			return nil, fmt.Errorf("%s:%d: malformed line", file, lineno)
+		}
+		passwords[key] = password
+	}
+	return passwords, nil
+}
+
+// lookupPassword returns the password saved for the server URL key
+// by "mote login".
+func lookupPassword(key string) (string, error) {
+	passwords, err := readPasswords()
+	if err != nil {
+		return "", err
+	}
+	if passwords[key] == "" {
+		return "", fmt.Errorf("no password for %s; run mote login %s", key, key)
+	}
+	return passwords[key], nil
+}
+
+// setPassword adds or replaces the password for the server URL key,
+// rewriting password.txt.
+func setPassword(key, password string) error {
+	passwords, err := readPasswords()
+	if err != nil {
+		return err
+	}
+	passwords[key] = password
+	var b strings.Builder
+	for _, k := range slices.Sorted(maps.Keys(passwords)) {
+		fmt.Fprintf(&b, "%s %s\n", k, passwords[k])
+	}
+	file, err := passwordFile()
+	if err != nil {
+		return err
+	}
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, []byte(b.String()), 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, file); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// cmdAlias implements "mote alias [name [URL]]".
+func cmdAlias(args []string) {
+	switch len(args) {
+	case 0:
+		aliases, err := readAliases()
+		if err != nil {
+			log.Fatal(err)
+		}
+		names := slices.Sorted(maps.Keys(aliases))
+		w := 0
+		for _, name := range names {
+			w = max(w, len(name))
+		}
+		for _, name := range names {
+			fmt.Printf("%-*s %s\n", w, name, aliases[name])
+		}
+	case 1:
+		url, err := lookupAlias(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+		if url == "" {
+			log.Fatalf("no alias for %s", args[0])
+		}
+		fmt.Printf("%s\n", url)
+	case 2:
+		if err := setAlias(args[0], args[1]); err != nil {
+			log.Fatal(err)
+		}
+	default:
+		// An exec:// command line may arrive as many arguments.
+		if !isExecURL(args[1]) {
+			usage()
+		}
+		if err := setAlias(args[0], strings.Join(args[1:], " ")); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// A config is the mote configuration file, config.json.
+type config struct {
+	Aliases map[string]*alias `json:",omitzero"`
+	Hooks   []string          `json:",omitzero"` // go_$GOOS_$GOARCH_exec hooks installed by go-setup (see gosetup.go)
+}
+
+// An alias is a named server, with settings for the commands run there.
+type alias struct {
+	URL      string   // server URL
+	Fallback []string `json:",omitzero"` // server URLs to try in order when URL cannot be reached
+	Group    string   `json:",omitzero"` // group the alias belongs to
+	Env      []string `json:",omitzero"` // environment variables for commands
+	Exclude  []string `json:",omitzero"` // patterns naming files not to upload (see uploadList)
+	Timeout  duration `json:",omitzero"` // limit on a command's running time
+	Hex      bool     `json:",omitzero"` // run ssh:// and exec:// servers in hex (see hex.go)
+
+	// guessed marks an alias that resolveAliases made up, as a
+	// stand-in, for a GOOS-GOARCH name with none. It is not saved.
+	guessed bool
+}
+
+// A duration is a time.Duration written in JSON as a string like "10m".
+type duration time.Duration
+
+func (d duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+func (d *duration) UnmarshalText(text []byte) error {
+	x, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = duration(x)
+	return nil
+}
+
+func configFile() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "config.json"), nil
+}
+
+// readConfig reads config.json. If there is none, it migrates the
+// alias definitions from aliases.txt, the file that held them before
+// config.json existed.
+func readConfig() (*config, error) {
+	file, err := configFile()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return migrateAliases(file)
+	}
+	if err != nil {
+		return nil, err
+	}
+	cfg := new(config)
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	for name, a := range cfg.Aliases {
+		if a == nil || a.URL == "" {
+			return nil, fmt.Errorf("%s: alias %s has no URL", file, name)
+		}
+	}
+	return cfg, nil
+}
+
+// writeConfig replaces config.json with cfg.
+func writeConfig(cfg *config) error {
+	data, err := json.MarshalIndent(cfg, "", "\t")
+	if err != nil {
+		return err
+	}
+	file, err := configFile()
+	if err != nil {
+		return err
+	}
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o666); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, file); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// migrateAliases converts aliases.txt, if it exists, to the config
+// file cfgFile, renaming it to aliases.txt.old, and returns the new
+// configuration. Each line of aliases.txt is an alias name and a URL,
+// separated by spaces; blank lines and lines beginning with # are
+// ignored.
+func migrateAliases(cfgFile string) (*config, error) {
+	cfg := &config{Aliases: make(map[string]*alias)}
+	file := filepath.Join(filepath.Dir(cfgFile), "aliases.txt")
+	data, err := os.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cfg, nil
+		}
+		return nil, err
+	}
+	lineno := 0
+	for line := range strings.Lines(string(data)) {
+		lineno++
+		f := strings.Fields(line)
+		if len(f) == 0 || strings.HasPrefix(f[0], "#") {
+			continue
+		}
+		if len(f) > 2 && isExecURL(f[1]) {
+			// The rest of the line is an exec:// command line.
+			url := strings.TrimPrefix(strings.TrimSpace(line), f[0])
+			f = []string{f[0], strings.TrimSpace(url)}
+		}
+		if len(f) != 2 {
+			return nil, fmt.Errorf("%s:%d: malformed line: %s", file, lineno, strings.TrimSpace(line))
+		}
+		cfg.Aliases[f[0]] = &alias{URL: f[1]}
+	}
+	if err := writeConfig(cfg); err != nil {
+		return nil, err
+	}
+	// Another mote may have migrated the file already.
+	if err := os.Rename(file, file+".old"); err == nil {
+		log.Printf("moved aliases from %s to %s", file, cfgFile)
+	}
+	return cfg, nil
+}
+
+// lookup returns the aliases for name: the alias of that name, or,
+// if there is none, the aliases in the group of that name, in name
+// order, or, if there are none, nil.
+func (cfg *config) lookup(name string) []*alias {
+	if a := cfg.Aliases[name]; a != nil {
+		return []*alias{a}
+	}
+	var list []*alias
+	for _, n := range slices.Sorted(maps.Keys(cfg.Aliases)) {
+		if cfg.Aliases[n].Group == name {
+			list = append(list, cfg.Aliases[n])
+		}
+	}
+	return list
+}
+
+// readAliases returns the alias definitions, mapping names to URLs.
+func readAliases() (map[string]string, error) {
+	cfg, err := readConfig()
+	if err != nil {
+		return nil, err
+	}
+	aliases := make(map[string]string)
+	for name, a := range cfg.Aliases {
+		aliases[name] = a.URL
+	}
+	return aliases, nil
+}
+
+// lookupAlias returns the URL for the named alias,
+// or "" if there is no such alias.
+func lookupAlias(name string) (string, error) {
+	a, err := lookupAliasConfig(name)
+	if a == nil || err != nil {
+		return "", err
+	}
+	return a.URL, nil
+}
+
+// lookupAliasConfig returns the named alias, or nil if there is none.
+func lookupAliasConfig(name string) (*alias, error) {
+	cfg, err := readConfig()
+	if err != nil {
+		return nil, err
+	}
+	return cfg.Aliases[name], nil
+}
+
+// setAlias sets the URL for the alias name, creating the alias if
+// needed and keeping any other settings it has, and rewrites config.json.
+func setAlias(name, url string) error {
+	cfg, err := readConfig()
+	if err != nil {
+		return err
+	}
+	if cfg.Aliases == nil {
+		cfg.Aliases = make(map[string]*alias)
+	}
+	if a := cfg.Aliases[name]; a != nil {
+		a.URL = url
+	} else {
+		cfg.Aliases[name] = &alias{URL: url}
+	}
+	return writeConfig(cfg)
+}
@@ -0,0 +1,223 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mote
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeConfigFile writes the text of config.json.
+func writeConfigFile(t *testing.T, text string) {
+	t.Helper()
+	file, err := configFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, []byte(text), 0o666); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMigrateAliases(t *testing.T) {
+	setupDirs(t)
+	dir, err := configDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := filepath.Join(dir, "aliases.txt")
+	text := "# servers\nkremvax ssh://kremvax\n\npod exec://kubectl exec -i pod -- mote serve -\n"
+	if err := os.WriteFile(old, []byte(text), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	aliases, err := readAliases()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(aliases) != 2 || aliases["kremvax"] != "ssh://kremvax" || aliases["pod"] != "exec://kubectl exec -i pod -- mote serve -" {
+		t.Errorf("migrated aliases = %v", aliases)
+	}
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Errorf("aliases.txt still exists after migration")
+	}
+	if _, err := os.Stat(old + ".old"); err != nil {
+		t.Errorf("aliases.txt.old: %v", err)
+	}
+
+	// Setting a URL keeps the alias's other settings.
+	writeConfigFile(t, `{"Aliases": {"kremvax": {"URL": "ssh://kremvax", "Env": ["A=1"], "Timeout": "10m"}}}`)
+	if err := setAlias("kremvax", "tcp://kremvax:6683"); err != nil {
+		t.Fatal(err)
+	}
+	a, err := lookupAliasConfig("kremvax")
+	if err != nil || a == nil {
+		t.Fatalf("lookupAliasConfig = %v, %v", a, err)
+	}
+	if a.URL != "tcp://kremvax:6683" || len(a.Env) != 1 || time.Duration(a.Timeout) != 10*time.Minute {
+		t.Errorf("alias after setAlias = %+v", a)
+	}
+}
+
+func TestConfigErrors(t *testing.T) {
+	setupDirs(t)
+	for _, text := range []string{
+		`{"Aliases": {"kremvax": {}}}`,
+		`{"Aliases": {"kremvax": {"URL": "ssh://kremvax", "Timeout": "soon"}}}`,
+		`{"Aliases": `,
+	} {
+		writeConfigFile(t, text)
+		if _, err := readConfig(); err == nil {
+			t.Errorf("readConfig(%s) succeeded, want error", text)
+		}
+	}
+}
+
+// TestConfigDirError checks that an unusable configuration directory
+// is an error for the caller, not the end of the program: the client
+// package reaches readConfig when it dials.
+func TestConfigDirError(t *testing.T) {
+	setupDirs(t)
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0o666); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("MOTECONFIG", filepath.Join(file, "mote"))
+	if _, err := readConfig(); err == nil || !strings.Contains(err.Error(), file) {
+		t.Errorf("readConfig: %v, want error naming %s", err, file)
+	}
+	if _, err := resolveServer("kremvax", "echo"); err == nil || !strings.Contains(err.Error(), file) {
+		t.Errorf("resolveServer: %v, want error naming %s", err, file)
+	}
+}
+
+func TestResolveGroup(t *testing.T) {
+	setupDirs(t)
+	writeConfigFile(t, `{"Aliases": {
+		"b": {"URL": "ssh://b", "Group": "pool"},
+		"a": {"URL": "ssh://a", "Group": "pool"},
+		"pool2": {"URL": "ssh://c", "Group": "pool"}
+	}}`)
+	if url, err := resolveServer("pool", "echo"); err != nil || url != "ssh://a" {
+		t.Errorf("resolveServer(pool) = %q, %v, want ssh://a", url, err)
+	}
+}
+
+func TestDialAliasFallback(t *testing.T) {
+	setupDirs(t)
+	bad := "unix://" + filepath.ToSlash(filepath.Join(t.TempDir(), "missing.sock"))
+	conn, url, err := dialAlias(&alias{URL: bad, Fallback: []string{"local://"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if url != "local://" {
+		t.Errorf("dialAlias used %s, want local://", url)
+	}
+	runConn(t, conn, []string{"echo", "fallback"}, "fallback\n")
+
+	_, _, err = dialAlias(&alias{URL: bad, Fallback: []string{bad + "2"}})
+	if err == nil || !strings.Contains(err.Error(), "missing.sock2") {
+		t.Errorf("dialAlias with no reachable server: %v, want errors naming both", err)
+	}
+}
+
+// fakeServer serves unix://path, answering each connection with an
+// Info response reporting load and then hanging up.
+func fakeServer(t *testing.T, load int) string {
+	path := filepath.Join(t.TempDir(), "s")
+	ln, err := listenUnix(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			if serverHandshake(c) == nil {
+				newConn(c).writePacket(&Response{Type: "Info", GOOS: "linux", GOARCH: "amd64", Load: load}, nil)
+			}
+			c.Close()
+		}
+	}()
+	return "unix://" + filepath.ToSlash(path)
+}
+
+func TestDialGroup(t *testing.T) {
+	setupDirs(t)
+	busy, idle := fakeServer(t, 3), fakeServer(t, 1)
+	gone := "unix://" + filepath.ToSlash(filepath.Join(t.TempDir(), "gone.sock"))
+	writeConfigFile(t, fmt.Sprintf(`{"Aliases": {
+		"a": {"URL": %q, "Group": "pool"},
+		"b": {"URL": %q, "Group": "pool", "Env": ["B=1"]},
+		"c": {"URL": %q, "Group": "pool"}
+	}}`, busy, idle, gone))
+	list, err := resolveAliases("pool", "")
+	if err != nil || len(list) != 3 {
+		t.Fatalf("resolveAliases(pool) = %v, %v, want 3 aliases", list, err)
+	}
+	for range 5 {
+		conn, a, url, err := dialAliases(list)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn.Close()
+		if url != idle || len(a.Env) != 1 || conn.Load != 1 {
+			t.Errorf("dialAliases chose %s (load %d), want %s", url, conn.Load, idle)
+		}
+	}
+
+	// When no server answers, the errors name them all.
+	_, _, _, err = dialAliases([]*alias{{URL: gone}, {URL: gone + "2"}})
+	if err == nil || !strings.Contains(err.Error(), "gone.sock2") {
+		t.Errorf("dialAliases with no reachable server: %v", err)
+	}
+}
+
+func TestSSHHex(t *testing.T) {
+	setupDirs(t)
+	mockPATH(t, "ssh")
+	conn, url, err := dialAlias(&alias{URL: "ssh://kremvax", Hex: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if url != "ssh+hex://kremvax" {
+		t.Errorf("dialAlias used %s, want ssh+hex://kremvax", url)
+	}
+	runConn(t, conn, []string{"echo", "over hex"}, "over hex\n")
+}
+
+func TestAliasRunSettings(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	setupDirs(t)
+	mockPATH(t, "mote")
+	writeConfigFile(t, `{"Aliases": {
+		"here": {"URL": "local://", "Env": ["MOTE_TEST_VAR=from-alias"]},
+		"slow": {"URL": "local://", "Timeout": "100ms"}
+	}}`)
+	out, err := exec.Command("mote", "@here", "sh", "-c", "echo $MOTE_TEST_VAR").CombinedOutput()
+	if err != nil || string(out) != "from-alias\n" {
+		t.Errorf("mote @here: %q, %v, want from-alias", out, err)
+	}
+	start := time.Now()
+	out, err = exec.Command("mote", "@slow", "sleep", "10").CombinedOutput()
+	if err == nil || !strings.Contains(string(out), "timed out after 100ms") {
+		t.Errorf("mote @slow: %q, %v, want timeout", out, err)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("mote @slow took %v", d)
+	}
+}
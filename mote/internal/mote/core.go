@@ -0,0 +1,206 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mote
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Crash artifacts.
+//
+// A Go test that dies with a signal leaves only its output behind, which
+// is rarely enough to debug a crash on a machine one cannot log in to.
+// With the -core flag, the server runs the command with GOTRACEBACK=crash
+// and core dumps enabled; if the command dies with a signal, the server
+// looks for the core file it left, either in the command's directory or
+// where the system puts core files, and sends it back, along with the
+// binary that crashed, in Core responses before Exit. The client saves
+// the two side by side, ready for dlv core or gdb. See ../../protocol.md.
+
+// coreChunk is the most data sent in one Core response.
+const coreChunk = 1 << 20
+
+// Kinds of file sent in Core responses.
+const (
+	coreDump = "core" // the core dump
+	coreExe  = "exe"  // the binary that crashed
+)
+
+// coreEnv is the environment variable that makes a Go program dump
+// core when it crashes.
+const coreEnv = "GOTRACEBACK=crash"
+
+// sendCore sends the file f to the client as Core responses
+// of the given kind.
+func sendCore(conn *Conn, kind string, f *os.File) error {
+	buf := make([]byte, coreChunk)
+	for {
+		n, err := io.ReadFull(f, buf)
+		if n > 0 {
+			if err := conn.writePacket(&Response{Type: "Core", Core: kind}, buf[:n]); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// serveCore sends the client the core dump left by the command with
+// the given pid, which ran in dir from the time started, along with
+// exe, the binary it ran. If there is no core dump to send, or either
+// file cannot be opened, serveCore sends nothing; the client reports
+// that no core dump arrived. An error is a failure partway through.
+func serveCore(conn *Conn, dir string, pid int, started time.Time, exe string) error {
+	name := findCore(dir, pid, started)
+	if name == "" {
+		return nil
+	}
+	core, err := os.Open(name)
+	if err != nil {
+		return nil
+	}
+	defer core.Close()
+	bin, err := os.Open(exe)
+	if err != nil {
+		return nil
+	}
+	defer bin.Close()
+	if err := sendCore(conn, coreDump, core); err != nil {
+		return err
+	}
+	return sendCore(conn, coreExe, bin)
+}
+
+// newerFile reports whether path is a regular file modified at or after t.
+func newerFile(path string, t time.Time) bool {
+	fi, err := os.Stat(path)
+	// Allow for file systems that round modification times.
+	return err == nil && fi.Mode().IsRegular() && !fi.ModTime().Before(t.Add(-2*time.Second))
+}
+
+// coreGlob returns the files that the Linux core_pattern pat could have
+// named for the process pid, which ran in dir. Only %p, the pid, is
+// known here; the other specifiers match anything.
+func coreGlob(dir, pat, pid string, usesPid bool) []string {
+	var b strings.Builder
+	sawPid := false
+	for i := 0; i < len(pat); i++ {
+		c := pat[i]
+		switch {
+		case c == '%' && i+1 < len(pat):
+			i++
+			switch pat[i] {
+			case '%':
+				b.WriteByte('%')
+			case 'p':
+				b.WriteString(pid)
+				sawPid = true
+			default:
+				b.WriteByte('*')
+			}
+		case c == '*' || c == '?' || c == '[' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	glob := b.String()
+	if !sawPid && usesPid {
+		glob += "." + pid
+	}
+	if !filepath.IsAbs(glob) {
+		glob = filepath.Join(dir, glob)
+	}
+	list, _ := filepath.Glob(glob)
+	return list
+}
+
+// A coreSaver saves the files sent in Core responses in a directory
+// on the client, as name.core and name, where name is the base name of
+// the command.
+type coreSaver struct {
+	dir   string
+	name  string
+	files map[string]*os.File
+	err   error
+	done  bool // close has been called
+}
+
+func newCoreSaver(dir, cmd string) *coreSaver {
+	return &coreSaver{dir: dir, name: filepath.Base(filepath.FromSlash(cmd)), files: make(map[string]*os.File)}
+}
+
+// path returns the client path for files of the given kind.
+func (s *coreSaver) path(kind string) string {
+	if kind == coreDump {
+		return filepath.Join(s.dir, s.name+".core")
+	}
+	return filepath.Join(s.dir, s.name)
+}
+
+// write adds data to the file of the given kind, creating it if needed.
+// After an error, write does nothing; close reports the error.
+func (s *coreSaver) write(kind string, data []byte) {
+	if s.err != nil {
+		return
+	}
+	if kind != coreDump && kind != coreExe {
+		s.err = fmt.Errorf("unknown Core file %q", kind)
+		return
+	}
+	f := s.files[kind]
+	if f == nil {
+		if err := os.MkdirAll(s.dir, 0o777); err != nil {
+			s.err = err
+			return
+		}
+		mode := os.FileMode(0o666)
+		if kind == coreExe {
+			mode = 0o777
+		}
+		var err error
+		f, err = os.OpenFile(s.path(kind), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+		if err != nil {
+			s.err = err
+			return
+		}
+		s.files[kind] = f
+	}
+	if _, err := f.Write(data); err != nil {
+		s.err = err
+	}
+}
+
+// close closes the saved files, returning the path of the core dump
+// ("" if none arrived) and the first error saving them.
+// Calls after the first return the same results.
+func (s *coreSaver) close() (string, error) {
+	if !s.done {
+		s.done = true
+		for _, f := range s.files {
+			if err := f.Close(); err != nil && s.err == nil {
+				s.err = err
+			}
+		}
+	}
+	if s.err != nil {
+		return "", fmt.Errorf("saving core dump: %v", s.err)
+	}
+	if s.files[coreDump] == nil {
+		return "", nil
+	}
+	return s.path(coreDump), nil
+}
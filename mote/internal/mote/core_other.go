@@ -0,0 +1,18 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !unix
+
+package mote
+
+import (
+	"os/exec"
+	"time"
+)
+
+// allowCores is a no-op on systems without core file limits.
+func allowCores(c *exec.Cmd) {}
+
+// findCore returns "": the system leaves no core files to find.
+func findCore(dir string, pid int, started time.Time) string { return "" }
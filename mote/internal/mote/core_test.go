@@ -0,0 +1,88 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mote
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestCoreDump(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no core dumps on windows")
+	}
+	setupDirs(t)
+	mockPATH(t, "crash")
+	dir := t.TempDir()
+	var errb bytes.Buffer
+	w, err := startServeClient(t, "").Run(t.Context(), &Exec{
+		Args:    []string{"crash"},
+		Dir:     "/mote-test",
+		CoreDir: dir,
+		Stderr:  &errb,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Code >= 0 {
+		t.Fatalf("code=%d, want crash; stderr:\n%s", w.Code, errb.String())
+	}
+	if w.Core == "" {
+		// The system decides whether a core dump is written at all.
+		t.Skip("no core dump from crash")
+	}
+	if w.Core != filepath.Join(dir, "crash.core") {
+		t.Errorf("Core = %q, want %q", w.Core, filepath.Join(dir, "crash.core"))
+	}
+	if fi, err := os.Stat(w.Core); err != nil || fi.Size() == 0 {
+		t.Errorf("core dump not saved: %v", err)
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := os.ReadFile(exe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(filepath.Join(dir, "crash"))
+	if err != nil || !bytes.Equal(got, want) {
+		t.Errorf("saved binary differs from the binary that crashed (err=%v)", err)
+	}
+}
+
+func TestCoreGlob(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"core.123", "core.1234", "core.crash.123.host", "core.crash.9.host", "a[b].123"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		pat     string
+		usesPid bool
+		want    []string
+	}{
+		{"core", true, []string{"core.123"}},
+		{"core.%p", false, []string{"core.123"}},
+		{"core.%e.%p.%h", false, []string{"core.crash.123.host"}},
+		{"core.%e.%p.%%", false, nil},
+		{"a[b].%p", false, []string{"a[b].123"}},
+		{filepath.ToSlash(dir) + "/core.%p", false, []string{"core.123"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, name := range coreGlob(dir, tt.pat, "123", tt.usesPid) {
+			got = append(got, filepath.Base(name))
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("coreGlob(%q, %v) = %q, want %q", tt.pat, tt.usesPid, got, tt.want)
+		}
+	}
+}
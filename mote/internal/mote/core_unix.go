@@ -0,0 +1,121 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build unix
+
+package mote
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// coreLimitScript raises the shell's core file size limit as far as it
+// may go and then execs its arguments, which inherit the limit, with
+// $0 as the command's argv[0] if the shell's exec has -a to set it.
+const coreLimitScript = `ulimit -c unlimited 2>/dev/null || ulimit -c "$(ulimit -H -c)"
+if (exec -a sh true) 2>/dev/null; then exec -a "$0" "$@"; fi
+exec "$@"`
+
+// allowCores arranges for the command c, once started, to run with its
+// core file size limit raised as far as it may go, so that it can dump
+// core. Go has no way to set a limit for a child alone, and the server
+// must not raise its own, which every later command would inherit, so
+// c runs under a shell that raises the limit and execs the command in
+// its place, keeping the pid and, with bash or another shell whose
+// exec has -a, argv[0]. (Under a shell without it, such as dash,
+// argv[0] becomes the command's path.) The limit only matters to a
+// process that crashes with core dumps asked for, as Go programs do
+// only with GOTRACEBACK=crash.
+func allowCores(c *exec.Cmd) {
+	if c.Err != nil {
+		return // let Start report it
+	}
+	sh := "/bin/sh"
+	if runtime.GOOS == "android" {
+		sh = "/system/bin/sh"
+	}
+	if bash, err := exec.LookPath("bash"); err == nil {
+		sh = bash
+	}
+	c.Args = append([]string{"sh", "-c", coreLimitScript, c.Args[0], c.Path}, c.Args[1:]...)
+	c.Path = sh
+}
+
+// coredumpctlTimeout bounds waiting for systemd-coredump
+// to finish storing a core dump.
+const coredumpctlTimeout = 10 * time.Second
+
+// findCore returns the path of the core file left by the process pid,
+// which ran in dir from the time started, or "" if there is none.
+// It looks in dir, where a core file goes by default, for the names the
+// kernel and QEMU use, and then where the system puts core files: on
+// Linux, where /proc/sys/kernel/core_pattern says, including in the
+// store of systemd-coredump; on macOS, in /cores.
+func findCore(dir string, pid int, started time.Time) string {
+	p := strconv.Itoa(pid)
+	names := []string{
+		filepath.Join(dir, "core"),
+		filepath.Join(dir, "core."+p),
+	}
+	if qemu, _ := filepath.Glob(filepath.Join(dir, "qemu_*_"+p+".core")); len(qemu) > 0 {
+		names = append(names, qemu...)
+	}
+	switch runtime.GOOS {
+	case "darwin", "ios":
+		names = append(names, "/cores/core."+p)
+	case "linux", "android":
+		pattern, err := os.ReadFile("/proc/sys/kernel/core_pattern")
+		if err != nil {
+			break
+		}
+		pat := strings.TrimSpace(string(pattern))
+		if strings.HasPrefix(pat, "|") {
+			if strings.Contains(pat, "systemd-coredump") {
+				if core := coredumpctl(dir, p); core != "" {
+					return core
+				}
+			}
+			break
+		}
+		usesPid, _ := os.ReadFile("/proc/sys/kernel/core_uses_pid")
+		names = append(names, coreGlob(dir, pat, p, strings.TrimSpace(string(usesPid)) == "1")...)
+	}
+	for _, name := range names {
+		if newerFile(name, started) {
+			return name
+		}
+	}
+	return ""
+}
+
+// coredumpctl asks systemd-coredump for the core dump of the process
+// pid, saving it in dir. It returns the file's path, or "" if the core
+// dump cannot be had. The dump may still be on its way to the store
+// when the command's exit is seen, so coredumpctl tries for a while.
+func coredumpctl(dir, pid string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), coredumpctlTimeout)
+	defer cancel()
+	file := filepath.Join(dir, "core."+pid)
+	for {
+		err := exec.CommandContext(ctx, "coredumpctl", "--quiet", "dump", "--output="+file, pid).Run()
+		if err == nil {
+			return file
+		}
+		if _, ok := err.(*exec.ExitError); !ok {
+			return "" // no coredumpctl, or out of time
+		}
+		select {
+		case <-ctx.Done():
+			return ""
+		case <-time.After(time.Second):
+		}
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build unix
+
+package mote
+
+import (
+	"os/exec"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestAllowCores(t *testing.T) {
+	var old syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_CORE, &old); err != nil {
+		t.Skip(err)
+	}
+	if old.Max == 0 {
+		t.Skip("hard core file size limit is 0")
+	}
+	lim := old
+	lim.Cur = 0
+	if err := syscall.Setrlimit(syscall.RLIMIT_CORE, &lim); err != nil {
+		t.Skip(err)
+	}
+	defer syscall.Setrlimit(syscall.RLIMIT_CORE, &old)
+
+	c := exec.Command("sh", "-c", "ulimit -c")
+	allowCores(c)
+	out, err := c.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(string(out)); got == "0" {
+		t.Errorf("command's core file size limit is 0, want raised")
+	}
+	// The limit is the command's alone.
+	var now syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_CORE, &now); err != nil {
+		t.Fatal(err)
+	}
+	if now.Cur != 0 {
+		t.Errorf("server's core file size limit is %d, want 0 as before", now.Cur)
+	}
+
+	// The command keeps its argv[0], where the shell can keep it.
+	if _, err := exec.LookPath("bash"); err != nil {
+		return
+	}
+	c = exec.Command("sh", "-c", `tr '\0' ' ' </proc/$$/cmdline`)
+	c.Args[0] = "argv0"
+	allowCores(c)
+	out, err = c.Output()
+	if err != nil {
+		t.Skip(err) // no /proc
+	}
+	if got := strings.Fields(string(out)); len(got) == 0 || got[0] != "argv0" {
+		t.Errorf("command line %q, want argv[0] argv0", out)
+	}
+}
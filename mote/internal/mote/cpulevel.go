@@ -0,0 +1,129 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mote
+
+import (
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/cpu"
+)
+
+// Microarchitecture levels.
+//
+// A Go binary built with GOAMD64=v3, GOARM64=v8.1, or GOARM=7 uses
+// instructions that older CPUs of its architecture lack, and on one of
+// those it dies with SIGILL, often well into a test run. The server
+// reports the highest level its CPU supports in Info, and the client
+// checks the binary's level against it before uploading anything.
+
+// levelVar maps a GOARCH to the environment variable setting its level.
+var levelVar = map[string]string{
+	"amd64": "GOAMD64",
+	"arm64": "GOARM64",
+	"arm":   "GOARM",
+}
+
+// cpuLevel returns the highest level of this machine's architecture
+// that its CPU supports, or "" for an architecture without levels.
+func cpuLevel() string {
+	switch runtime.GOARCH {
+	case "amd64":
+		// x/sys/cpu does not report LZCNT, MOVBE, or F16C, which v3
+		// also requires, but no CPU with AVX2 and BMI2 lacks them.
+		x := &cpu.X86
+		switch {
+		case !(x.HasSSE3 && x.HasSSSE3 && x.HasSSE41 && x.HasSSE42 && x.HasPOPCNT && x.HasCX16):
+			return "v1"
+		case !(x.HasAVX && x.HasAVX2 && x.HasBMI1 && x.HasBMI2 && x.HasFMA && x.HasOSXSAVE):
+			return "v2"
+		case !(x.HasAVX512F && x.HasAVX512BW && x.HasAVX512CD && x.HasAVX512DQ && x.HasAVX512VL):
+			return "v3"
+		}
+		return "v4"
+	case "arm64":
+		// The only instructions Go uses beyond v8.0 are v8.1's
+		// atomics, so v8.1 is as high as the server needs to say.
+		if cpu.ARM64.HasATOMICS {
+			return "v8.1"
+		}
+		return "v8.0"
+	case "arm":
+		switch {
+		case cpu.ARM.HasVFPv3:
+			return "7"
+		case cpu.ARM.HasVFP:
+			return "6"
+		}
+		return "5"
+	}
+	return ""
+}
+
+// levelRank returns the position of level among the levels of goarch
+// that make a difference to the code Go generates. It reports false
+// for a level it does not understand.
+func levelRank(goarch, level string) (int, bool) {
+	level, opts, _ := strings.Cut(level, ",")
+	switch goarch {
+	case "amd64":
+		n, err := strconv.Atoi(strings.TrimPrefix(level, "v"))
+		return n, err == nil && strings.HasPrefix(level, "v") && 1 <= n && n <= 4
+	case "arm64":
+		major, minor, ok := strings.Cut(strings.TrimPrefix(level, "v"), ".")
+		ma, err1 := strconv.Atoi(major)
+		mi, err2 := strconv.Atoi(minor)
+		if !ok || err1 != nil || err2 != nil || !strings.HasPrefix(level, "v") {
+			return 0, false
+		}
+		if ma > 8 || mi >= 1 || strings.Contains(","+opts+",", ",lse,") {
+			return 1, true // needs v8.1 atomics
+		}
+		return 0, true
+	case "arm":
+		n, err := strconv.Atoi(level)
+		return n, err == nil && 5 <= n && n <= 7
+	}
+	return 0, false
+}
+
+// levelSupports reports whether a CPU at level have, as reported by
+// cpuLevel, runs code built for level need. An unknown level on either
+// side supports everything, leaving the question to the server.
+func levelSupports(goarch, have, need string) bool {
+	h, ok1 := levelRank(goarch, have)
+	n, ok2 := levelRank(goarch, need)
+	return !ok1 || !ok2 || n <= h
+}
+
+// checkBinary checks that the binary file can run on the server at the
+// other end of conn, before anything is uploaded, so that a binary for
+// the wrong system fails with an explanation instead of an exec format
+// error or an illegal instruction.
+func checkBinary(conn *Conn, file string) error {
+	goos, goarch, err := binaryOSArch(file)
+	if err != nil || conn.GOOS == "" || conn.GOARCH == "" {
+		return nil // cannot tell; let the server try
+	}
+	if goos != conn.GOOS || !archRuns(conn.GOARCH, goarch) {
+		return fmt.Errorf("%s is a %s-%s binary, but the server is %s-%s", file, goos, goarch, conn.GOOS, conn.GOARCH)
+	}
+	if goarch != conn.GOARCH {
+		return nil
+	}
+	if need := binaryLevel(file); !levelSupports(goarch, conn.Level, need) {
+		return fmt.Errorf("%s was built with %s=%s, but the server's CPU supports only %s", file, levelVar[goarch], need, conn.Level)
+	}
+	return nil
+}
+
+// archRuns reports whether a machine of architecture host runs
+// binaries for goarch: its own, and the 32-bit forms of amd64 and arm64,
+// which most of those machines run too.
+func archRuns(host, goarch string) bool {
+	return host == goarch || host == "amd64" && goarch == "386" || host == "arm64" && goarch == "arm"
+}
@@ -0,0 +1,83 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mote
+
+import (
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestCPULevel(t *testing.T) {
+	level := cpuLevel()
+	if levelVar[runtime.GOARCH] == "" {
+		if level != "" {
+			t.Errorf("cpuLevel() = %q on %s, want none", level, runtime.GOARCH)
+		}
+		return
+	}
+	if _, ok := levelRank(runtime.GOARCH, level); !ok {
+		t.Errorf("cpuLevel() = %q, not a %s level", level, levelVar[runtime.GOARCH])
+	}
+}
+
+func TestLevelSupports(t *testing.T) {
+	for _, tt := range []struct {
+		goarch, have, need string
+		ok                 bool
+	}{
+		{"amd64", "v3", "v1", true},
+		{"amd64", "v3", "v3", true},
+		{"amd64", "v2", "v3", false},
+		{"amd64", "v4", "v3", true},
+		{"arm64", "v8.1", "v8.0", true},
+		{"arm64", "v8.1", "v9.3", true}, // nothing past v8.1 matters
+		{"arm64", "v8.0", "v8.1", false},
+		{"arm64", "v8.0", "v8.0,lse", false},
+		{"arm64", "v8.0", "v8.0,crypto", true},
+		{"arm", "7", "6", true},
+		{"arm", "6", "7", false},
+		{"arm", "6", "7,softfloat", false},
+		// Unknown levels leave it to the server.
+		{"amd64", "", "v4", true},
+		{"amd64", "v1", "", true},
+		{"amd64", "v1", "v9", true},
+		{"riscv64", "", "rva22u64", true},
+	} {
+		if ok := levelSupports(tt.goarch, tt.have, tt.need); ok != tt.ok {
+			t.Errorf("levelSupports(%s, %q, %q) = %v, want %v", tt.goarch, tt.have, tt.need, ok, tt.ok)
+		}
+	}
+}
+
+func TestCheckBinary(t *testing.T) {
+	// The test binary itself is a Go binary for this system.
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if levelVar[runtime.GOARCH] != "" && binaryLevel(exe) == "" {
+		t.Errorf("binaryLevel(test binary) = \"\", want its %s", levelVar[runtime.GOARCH])
+	}
+	other := "arm64"
+	if runtime.GOARCH == "arm64" {
+		other = "amd64"
+	}
+	for _, tt := range []struct {
+		server *Conn
+		err    string
+	}{
+		{&Conn{GOOS: runtime.GOOS, GOARCH: runtime.GOARCH, Level: cpuLevel()}, ""},
+		{&Conn{GOOS: runtime.GOOS, GOARCH: runtime.GOARCH}, ""}, // older server
+		{&Conn{GOOS: runtime.GOOS, GOARCH: other}, "binary, but the server is " + runtime.GOOS + "-" + other},
+		{&Conn{GOOS: "plan9", GOARCH: runtime.GOARCH}, "binary, but the server is plan9-" + runtime.GOARCH},
+	} {
+		err := checkBinary(tt.server, exe)
+		if tt.err == "" && err != nil || tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("checkBinary(test binary, %s-%s %s) = %v, want %q", tt.server.GOOS, tt.server.GOARCH, tt.server.Level, err, tt.err)
+		}
+	}
+}
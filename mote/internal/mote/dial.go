@@ -2,13 +2,14 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-package main
+package mote
 
 import (
 	"bytes"
 	"errors"
 	"fmt"
 	"io"
+	"math/rand/v2"
 	"net/url"
 	"os"
 	"os/exec"
@@ -20,23 +21,39 @@ import (
 // connection handshake and any encryption handshake, and reads the
 // server's initial Info response, returning a connection ready for Run.
 func dialServer(rawURL string) (*Conn, error) {
+	var rwc io.ReadWriteCloser
+	password := ""
+	// An exec:// URL holds a command line, which url.Parse rejects.
 	u, err := url.Parse(rawURL)
+	if isExecURL(rawURL) {
+		u, err = &url.URL{Scheme: "exec"}, nil
+	}
 	if err != nil {
 		return nil, fmt.Errorf("invalid server URL %s: %v", rawURL, err)
 	}
-	var rwc io.ReadWriteCloser
-	password := ""
 	switch u.Scheme {
 	default:
 		return nil, fmt.Errorf("unknown server URL scheme %s://", u.Scheme)
-	case "ssh":
-		rwc, err = dialSSH(u)
+	case "ssh", "ssh+hex":
+		// The ssh transport runs the handshake itself, to learn
+		// whether the server needs upgrading. See dialSSH.
+		return dialSSH(u)
+	case "exec":
+		rwc, err = dialExec(rawURL)
 	case "tcp":
 		rwc, password, err = dialTCP(u)
 	case "tail":
 		rwc, err = dialTail(u)
+	case "qemu":
+		rwc, err = dialQemu(u)
+	case "unix":
+		rwc, err = dialUnix(u)
+	case "local":
+		rwc, err = dialLocal(u)
+	case "ws", "wss":
+		rwc, password, err = dialWS(u)
 	case "gomote":
-		// Unlike the others, the gomote transport runs the handshake
+		// Like ssh, the gomote transport runs the handshake
 		// itself: when the direct connection fails it has a second way
 		// in to try, and only the handshake says whether the first
 		// one worked. See dialGomote.
@@ -52,6 +69,123 @@ func dialServer(rawURL string) (*Conn, error) {
 	return conn, nil
 }
 
+// dialAlias connects to the server for the alias a: the server at a.URL
+// or, if that cannot be reached, the first of a.Fallback that can.
+// It returns the connection and the URL of the server it reached.
+func dialAlias(a *alias) (*Conn, string, error) {
+	urls := append([]string{a.URL}, a.Fallback...)
+	var errs []error
+	for _, url := range urls {
+		if a.Hex {
+			url = hexURL(url)
+		}
+		conn, err := dialServer(url)
+		if err == nil {
+			return conn, url, nil
+		}
+		if len(urls) == 1 {
+			return nil, "", err
+		}
+		errs = append(errs, fmt.Errorf("%s: %v", url, err))
+	}
+	return nil, "", errors.Join(errs...)
+}
+
+// probeGrace is how long dialAliases waits, once one server in a group
+// has answered, for the others to answer too.
+const probeGrace = 500 * time.Millisecond
+
+// dialAliases connects to one of the servers for the aliases in list,
+// returning the connection, the alias for its server, and the URL it
+// reached. With more than one alias, as for a group, it dials them all
+// at once and keeps the connection to the least loaded server among
+// those that answer within probeGrace of the first, choosing at random
+// among equally loaded ones, so that commands started together spread
+// across the group. Servers that cannot be reached are skipped.
+func dialAliases(list []*alias) (*Conn, *alias, string, error) {
+	if len(list) == 1 {
+		conn, url, err := dialAlias(list[0])
+		return conn, list[0], url, err
+	}
+	type result struct {
+		conn *Conn
+		a    *alias
+		url  string
+		err  error
+	}
+	ch := make(chan result, len(list))
+	for _, a := range list {
+		go func() {
+			conn, url, err := dialAlias(a)
+			ch <- result{conn, a, url, err}
+		}()
+	}
+	var best result
+	var errs []error
+	var grace <-chan time.Time
+	ties := 0
+	pending := len(list)
+Wait:
+	for pending > 0 {
+		select {
+		case r := <-ch:
+			pending--
+			switch {
+			case r.err != nil:
+				errs = append(errs, fmt.Errorf("%s: %v", r.a.URL, r.err))
+				continue
+			case best.conn == nil || r.conn.Load < best.conn.Load:
+				if best.conn != nil {
+					best.conn.Close()
+				}
+				best, ties = r, 1
+			case r.conn.Load == best.conn.Load:
+				// Keep each of the equally loaded servers with
+				// equal probability.
+				ties++
+				if rand.IntN(ties) == 0 {
+					best.conn.Close()
+					best = r
+				} else {
+					r.conn.Close()
+				}
+			default:
+				r.conn.Close()
+			}
+			if grace == nil {
+				grace = time.After(probeGrace)
+			}
+		case <-grace:
+			break Wait
+		}
+	}
+	go func() {
+		// Hang up on the servers that answer too late.
+		for range pending {
+			if r := <-ch; r.conn != nil {
+				r.conn.Close()
+			}
+		}
+	}()
+	if best.conn == nil {
+		return nil, nil, "", errors.Join(errs...)
+	}
+	return best.conn, best.a, best.url, nil
+}
+
+// hexURL returns the URL for the hex form of the server URL url:
+// exec+hex:// for exec:// and ssh+hex:// for ssh://.
+// Other URLs have no hex form and are returned unchanged.
+func hexURL(url string) string {
+	if rest, ok := strings.CutPrefix(url, execScheme); ok {
+		return execHexScheme + rest
+	}
+	if rest, ok := strings.CutPrefix(url, "ssh://"); ok {
+		return "ssh+hex://" + rest
+	}
+	return url
+}
+
 // abortConn tears down a connection after a failed session, giving the
 // transport a chance to add its own diagnostics to err.
 func abortConn(rwc io.ReadWriteCloser, err error) error {
@@ -64,8 +198,8 @@ func abortConn(rwc io.ReadWriteCloser, err error) error {
 
 // clientConn runs the client side of the connection handshake and
 // optional encryption handshake on rwc and reads the server's initial
-// Info response, recording the server's GOOS and GOARCH in the
-// returned connection.
+// Info response, recording the server's GOOS, GOARCH, protocol version,
+// capabilities, and load in the returned connection.
 func clientConn(rwc io.ReadWriteCloser, password string) (*Conn, error) {
 	if err := clientHandshake(rwc); err != nil {
 		return nil, err
@@ -95,7 +229,8 @@ func clientConn(rwc io.ReadWriteCloser, password string) (*Conn, error) {
 	if resp.Type != "Info" {
 		return nil, fmt.Errorf("unexpected response type %q, want Info", resp.Type)
 	}
-	conn.GOOS, conn.GOARCH = resp.GOOS, resp.GOARCH
+	conn.GOOS, conn.GOARCH, conn.Level = resp.GOOS, resp.GOARCH, resp.Level
+	conn.Version, conn.Caps, conn.Load = resp.Version, resp.Caps, resp.Load
 	return conn, nil
 }
 
@@ -0,0 +1,169 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mote
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// A discovered is a mote node found on the tailnet by mote discover.
+type discovered struct {
+	name    string // tail:// name of the node
+	goos    string // from the server's Info, if err is nil
+	goarch  string
+	version int
+	err     error // why the node could not be reached
+}
+
+func (s *discovered) url() string { return "tail://" + s.name }
+
+// cmdDiscover implements "mote discover [-y]", which lists the mote
+// servers on the tailnet and offers to create aliases for them.
+func cmdDiscover(args []string) {
+	flags := flag.NewFlagSet("discover", flag.ExitOnError)
+	flags.Usage = usage
+	yes := flags.Bool("y", false, "create the aliases without asking")
+	flags.Parse(args)
+	if flags.NArg() != 0 {
+		usage()
+	}
+
+	name, err := clientTailName()
+	if err != nil {
+		log.Fatal(err)
+	}
+	peers, err := daemonPeers(name)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(peers) == 0 {
+		log.Fatalf("no mote nodes on the tailnet")
+	}
+	servers := probeTail(peers)
+
+	w := 0
+	for _, s := range servers {
+		w = max(w, len(s.url()))
+	}
+	for _, s := range servers {
+		if s.err != nil {
+			// Clients are tagged like servers, so an unreachable
+			// node is most likely a client, not a broken server.
+			fmt.Printf("%-*s not serving: %v\n", w, s.url(), s.err)
+			continue
+		}
+		fmt.Printf("%-*s %s-%s version %d\n", w, s.url(), s.goos, s.goarch, s.version)
+	}
+
+	cfg, err := readConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+	add := discoverAliases(cfg, servers)
+	if len(add) == 0 {
+		return
+	}
+	fmt.Printf("\nnew or updated aliases:\n")
+	names := slices.Sorted(maps.Keys(add))
+	w = 0
+	for _, n := range names {
+		w = max(w, len(n))
+	}
+	for _, n := range names {
+		a := add[n]
+		if a.Group != "" {
+			fmt.Printf("\t%-*s %s (group %s)\n", w, n, a.URL, a.Group)
+		} else {
+			fmt.Printf("\t%-*s %s\n", w, n, a.URL)
+		}
+	}
+	if !*yes {
+		fmt.Fprintf(os.Stderr, "create these aliases? [y/N] ")
+		sc := bufio.NewScanner(os.Stdin)
+		if !sc.Scan() || !strings.EqualFold(strings.TrimSpace(sc.Text()), "y") {
+			return
+		}
+	}
+	if cfg.Aliases == nil {
+		cfg.Aliases = make(map[string]*alias)
+	}
+	maps.Copy(cfg.Aliases, add)
+	if err := writeConfig(cfg); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// probeTail connects to the tail:// server for each name, concurrently,
+// and returns what each server's Info says about it, in name order.
+func probeTail(names []string) []*discovered {
+	servers := make([]*discovered, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		s := &discovered{name: name}
+		servers[i] = s
+		wg.Go(func() {
+			conn, err := dialServer(s.url())
+			if err != nil {
+				s.err = err
+				return
+			}
+			// Hanging up before Setup is an ordinary end to a session.
+			s.goos, s.goarch, s.version = conn.GOOS, conn.GOARCH, conn.Version
+			conn.Close()
+		})
+	}
+	wg.Wait()
+	return servers
+}
+
+// discoverAliases returns the aliases to add to cfg, or to replace in
+// it, for the servers: an alias for each server, by its tail:// name,
+// and a $GOOS-$GOARCH alias for each system: the server itself when it
+// is the only one, or else a group of the servers. A server's existing
+// alias for its tail:// URL joins the group, keeping its settings.
+// Other existing aliases and groups are left alone: an alias by the
+// server's name for some other URL is not the server, and one already
+// in a group cannot join another. Unreachable servers get no aliases.
+func discoverAliases(cfg *config, servers []*discovered) map[string]*alias {
+	add := make(map[string]*alias)
+	systems := make(map[string][]*discovered)
+	for _, s := range servers {
+		if s.err != nil || s.goos == "" || s.goarch == "" {
+			continue
+		}
+		if len(cfg.lookup(s.name)) == 0 {
+			add[s.name] = &alias{URL: s.url()}
+		}
+		sys := s.goos + "-" + s.goarch
+		systems[sys] = append(systems[sys], s)
+	}
+	for sys, list := range systems {
+		if len(cfg.lookup(sys)) != 0 || add[sys] != nil {
+			continue
+		}
+		if len(list) == 1 {
+			add[sys] = &alias{URL: list[0].url()}
+			continue
+		}
+		for _, s := range list {
+			if a := add[s.name]; a != nil {
+				a.Group = sys
+			} else if a := cfg.Aliases[s.name]; a != nil && a.URL == s.url() && a.Group == "" {
+				a := *a
+				a.Group = sys
+				add[s.name] = &a
+			}
+		}
+	}
+	return add
+}
@@ -0,0 +1,51 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mote
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDiscoverAliases(t *testing.T) {
+	cfg := &config{Aliases: map[string]*alias{
+		"s3":          {URL: "ssh://s3"},
+		"s4":          {URL: "tail://s4", Env: []string{"GOGC=off"}},
+		"s5":          {URL: "tail://s5", Group: "fast"},
+		"s6":          {URL: "ssh://s6"},
+		"windows-arm": {URL: "ssh://w"},
+	}}
+	servers := []*discovered{
+		{name: "s1", goos: "linux", goarch: "amd64", version: 1},
+		{name: "s2", goos: "linux", goarch: "amd64", version: 1},
+		{name: "s3", goos: "darwin", goarch: "arm64"},
+		{name: "s4", goos: "linux", goarch: "amd64", version: 1},
+		{name: "s5", goos: "linux", goarch: "amd64", version: 1},
+		{name: "s6", goos: "linux", goarch: "amd64", version: 1},
+		{name: "w1", goos: "windows", goarch: "arm", version: 1},
+		{name: "laptop", err: errors.New("connection refused")},
+	}
+	want := map[string]*alias{
+		// Several linux-amd64 servers make a group.
+		"s1": {URL: "tail://s1", Group: "linux-amd64"},
+		"s2": {URL: "tail://s2", Group: "linux-amd64"},
+		// The existing s4 alias joins it, keeping its settings. The
+		// s5 alias is in another group already, and the s6 alias is
+		// for another server, so both stay as they are.
+		"s4": {URL: "tail://s4", Env: []string{"GOGC=off"}, Group: "linux-amd64"},
+		// The existing s3 alias stays, but the system still gets one.
+		"darwin-arm64": {URL: "tail://s3"},
+		// The existing windows-arm alias stays.
+		"w1": {URL: "tail://w1"},
+	}
+	got := discoverAliases(cfg, servers)
+	if !reflect.DeepEqual(got, want) {
+		for name, a := range got {
+			t.Logf("%s: %+v", name, *a)
+		}
+		t.Errorf("discoverAliases: wrong aliases")
+	}
+}
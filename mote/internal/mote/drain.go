@@ -0,0 +1,175 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mote
+
+import (
+	"errors"
+	"io"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// Graceful shutdown.
+//
+// A server listening for sessions (see serveListener and
+// serveWSListener) that receives SIGTERM or SIGINT drains instead of
+// dropping its sessions: it stops accepting connections and ends the
+// sessions whose commands have not started, with an error, but lets
+// the running commands finish, for up to the -drain time, telling
+// their clients so with a Draining response (if they understand it).
+// Then it kills the commands still running. Their sessions end as
+// usual, sending Exit and removing their temporary trees, and the
+// server exits. A second signal kills the commands at once.
+
+// drainKillWait is how long a session whose command has been killed
+// for a drain has to end before its connection is closed under it,
+// as when the client has stopped reading the command's output.
+const drainKillWait = 5 * time.Second
+
+// errDraining is the error for a session ended by a drain before its
+// command started.
+var errDraining = errors.New("server is shutting down")
+
+// A drainer is the drain state of a server.
+type drainer struct {
+	mu         sync.Mutex
+	draining   bool
+	deadline   time.Time            // when the running commands' time is up
+	watches    map[*drainWatch]bool // sessions in progress
+	expireOnce sync.Once
+	expired    chan struct{} // closed when the running commands' time is up
+}
+
+func newDrainer() *drainer {
+	return &drainer{watches: make(map[*drainWatch]bool), expired: make(chan struct{})}
+}
+
+// serverDrain is the drain state of this server.
+// It is a variable for testing.
+var serverDrain = newDrainer()
+
+// start begins the drain, giving running commands timeout to finish.
+// Calls after the first do nothing.
+func (d *drainer) start(timeout time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.draining {
+		return
+	}
+	d.draining = true
+	d.deadline = time.Now().Add(timeout)
+	for w := range d.watches {
+		go w.drain()
+	}
+	time.AfterFunc(timeout, d.expire)
+}
+
+// started reports whether the drain has begun.
+func (d *drainer) started() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.draining
+}
+
+// expire ends the running commands' time early.
+func (d *drainer) expire() {
+	d.expireOnce.Do(func() { close(d.expired) })
+}
+
+// A drainWatch applies a drain to one session: before the session's
+// command starts, the drain fails the session; after, it tells the
+// client and, if the command outlasts the drain, kills it.
+type drainWatch struct {
+	d      *drainer
+	conn   *Conn
+	rw     io.Closer
+	done   chan struct{} // closed when the session ends
+	mu     sync.Mutex
+	cmd    *exec.Cmd     // the running command, once started
+	notify bool          // send the client a Draining response
+	exited chan struct{} // closed when the command exits
+	ended  bool          // the drain has ended the session
+}
+
+// watchDrain starts applying d to the session on conn, which runs
+// over rw. The caller must call stop when the session ends.
+func watchDrain(d *drainer, conn *Conn, rw io.Closer) *drainWatch {
+	w := &drainWatch{d: d, conn: conn, rw: rw, done: make(chan struct{})}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.draining {
+		go w.drain()
+	} else {
+		d.watches[w] = true
+	}
+	return w
+}
+
+// stop stops watching, at the end of the session.
+func (w *drainWatch) stop() {
+	w.d.mu.Lock()
+	delete(w.d.watches, w)
+	w.d.mu.Unlock()
+	close(w.done)
+}
+
+// start starts the command c, unless the drain has already ended the
+// session. Once c is running, a drain sends the client a Draining
+// response, if notify is set, and kills c if it has not closed exited
+// by the end of the drain.
+func (w *drainWatch) start(c *exec.Cmd, notify bool, exited chan struct{}) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.ended {
+		return errDraining
+	}
+	if err := c.Start(); err != nil {
+		return err
+	}
+	w.cmd, w.notify, w.exited = c, notify, exited
+	return nil
+}
+
+// drain applies the drain, once it has begun, to the session.
+func (w *drainWatch) drain() {
+	w.mu.Lock()
+	c := w.cmd
+	if c == nil {
+		// Nothing running: end the session. Closing the connection
+		// stops whatever the session is waiting for from the client.
+		w.ended = true
+		w.mu.Unlock()
+		w.conn.writePacket(&Response{Type: "Exit", Error: errDraining.Error()}, nil)
+		w.rw.Close()
+		return
+	}
+	w.mu.Unlock()
+	if w.notify {
+		w.d.mu.Lock()
+		grace := time.Until(w.d.deadline)
+		w.d.mu.Unlock()
+		w.conn.writePacket(&Response{Type: "Draining", Grace: grace}, nil)
+	}
+	select {
+	case <-w.exited:
+		return
+	case <-w.done:
+		return
+	case <-w.d.expired:
+	}
+	// The exited check avoids killing a reused pid after the command is gone.
+	select {
+	case <-w.exited:
+		return
+	default:
+		killGroup(c)
+	}
+	select {
+	case <-w.done:
+	case <-time.After(drainKillWait):
+		w.rw.Close()
+	}
+}
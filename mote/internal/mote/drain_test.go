@@ -0,0 +1,200 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mote
+
+import (
+	"bytes"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// newTestDrainer gives the test a drain state of its own.
+func newTestDrainer(t *testing.T) *drainer {
+	old := serverDrain
+	serverDrain = newDrainer()
+	t.Cleanup(func() { serverDrain = old })
+	return serverDrain
+}
+
+// runDraining runs the shell script on a new server session and, once
+// the script writes its first output, starts a drain with the given
+// timeout. It returns the Wait and the standard output and error.
+func runDraining(t *testing.T, d *drainer, script string, timeout time.Duration) (*Wait, string, string) {
+	t.Helper()
+	var mu sync.Mutex
+	var outb, errb bytes.Buffer
+	w, err := startServeClient(t, "").Run(t.Context(), &Exec{
+		Args: []string{"sh", "-c", script},
+		Dir:  "/mote-test",
+		Stdout: writerFunc(func(b []byte) (int, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			outb.Write(b)
+			d.start(timeout)
+			return len(b), nil
+		}),
+		Stderr: &errb,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	return w, outb.String(), errb.String()
+}
+
+func TestDrainFinish(t *testing.T) {
+	setupDirs(t)
+	d := newTestDrainer(t)
+	// A running command may finish, and its client hears of the drain.
+	w, stdout, stderr := runDraining(t, d, "echo started; sleep 0.5; echo done", time.Minute)
+	if w.Code != 0 || stdout != "started\ndone\n" {
+		t.Errorf("code=%d stdout=%q stderr=%q; want 0, started and done", w.Code, stdout, stderr)
+	}
+	if !strings.Contains(stderr, "server is shutting down") {
+		t.Errorf("stderr=%q, want drain notice", stderr)
+	}
+}
+
+func TestDrainKill(t *testing.T) {
+	setupDirs(t)
+	d := newTestDrainer(t)
+	// A command that outlasts the drain is killed.
+	start := time.Now()
+	w, stdout, stderr := runDraining(t, d, "echo started; sleep 300", 100*time.Millisecond)
+	if w.Code >= 0 {
+		t.Errorf("code=%d status=%q stdout=%q stderr=%q; want signal death", w.Code, w.Status, stdout, stderr)
+	}
+	if dt := time.Since(start); dt > 30*time.Second {
+		t.Errorf("drain took %v", dt)
+	}
+}
+
+func TestDrainBeforeStart(t *testing.T) {
+	setupDirs(t)
+	d := newTestDrainer(t)
+	// A session whose command has not started ends with an error.
+	conn := startServeClient(t, "")
+	err := conn.writePacket(&Request{Type: "Setup", Args: []string{"echo", "hi"}, Dir: "/mote-test"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var resp Response
+	if _, err := conn.readPacket(&resp); err != nil || resp.Type != "Ready" {
+		t.Fatalf("got %+v, %v; want Ready", resp, err)
+	}
+	d.start(time.Minute)
+	if _, err := conn.readPacket(&resp); err != nil || resp.Type != "Exit" || resp.Error != errDraining.Error() {
+		t.Fatalf("got %+v, %v; want Exit with %q", resp, err, errDraining)
+	}
+}
+
+func TestDrainSignal(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no SIGTERM on Windows")
+	}
+	setupDirs(t)
+	newTestDrainer(t)
+	path := filepath.Join(t.TempDir(), "mote.sock")
+	ln, err := listenUnix(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	testDrainSignal(t, "unix://"+filepath.ToSlash(path), func() error {
+		return serveListener(ln, "", nil)
+	})
+}
+
+func TestDrainSignalWS(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no SIGTERM on Windows")
+	}
+	setupDirs(t)
+	newTestDrainer(t)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	url := "ws://" + ln.Addr().String() + "/mote"
+	if err := setPassword(url, "s3cret"); err != nil {
+		t.Fatal(err)
+	}
+	testDrainSignal(t, url, func() error {
+		return serveWSListener(ln, wsHandler("/mote", "s3cret"))
+	})
+}
+
+// testDrainSignal starts serve, which serves url, runs a command there,
+// and sends this process SIGTERM while it runs. serve must return nil
+// once the command has finished, and url must no longer answer.
+func testDrainSignal(t *testing.T, url string, serve func() error) {
+	t.Helper()
+	served := make(chan error, 1)
+	go func() { served <- serve() }()
+
+	// The dial completes once the server is accepting, and so
+	// watching for signals, making it safe to send one.
+	conn, err := dialServer(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	started := make(chan bool, 1)
+	done := make(chan *Wait, 1)
+	go func() {
+		w, err := conn.Run(t.Context(), &Exec{
+			Args: []string{"sh", "-c", "echo started; sleep 0.5"},
+			Dir:  "/mote-test",
+			Stdout: writerFunc(func(b []byte) (int, error) {
+				select {
+				case started <- true:
+				default:
+				}
+				return len(b), nil
+			}),
+		})
+		if err != nil {
+			t.Error(err)
+		}
+		done <- w
+	}()
+	<-started
+	self, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := self.Signal(syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Errorf("serve = %v, want nil after drain", err)
+		}
+	case <-time.After(30 * time.Second):
+		t.Fatalf("serve did not return after SIGTERM")
+	}
+	// serve waits for the session, so the command is done.
+	select {
+	case w := <-done:
+		if w != nil && w.Code != 0 {
+			t.Errorf("command status %q, want success", w.Status)
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("command still running after serve returned")
+	}
+	if _, err := dialServer(url); err == nil {
+		t.Errorf("dial succeeded after drain")
+	}
+}
@@ -4,9 +4,12 @@
 
 //go:build !unix
 
-package main
+package mote
 
-import "os/exec"
+import (
+	"os"
+	"os/exec"
+)
 
 func setpgid(c *exec.Cmd) {}
 
@@ -19,3 +22,6 @@ func killGroup(c *exec.Cmd) {
 // detach is a no-op on systems without sessions: a process started
 // here already outlives its parent.
 func detach(c *exec.Cmd) {}
+
+// maxRSS returns 0: the system does not report memory use.
+func maxRSS(ps *os.ProcessState) int64 { return 0 }
@@ -4,10 +4,12 @@
 
 //go:build unix
 
-package main
+package mote
 
 import (
+	"os"
 	"os/exec"
+	"runtime"
 	"syscall"
 )
 
@@ -30,3 +32,17 @@ func killGroup(c *exec.Cmd) {
 func detach(c *exec.Cmd) {
 	c.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
 }
+
+// maxRSS returns the maximum resident set size of the exited process,
+// in bytes, or 0 if the system does not say.
+func maxRSS(ps *os.ProcessState) int64 {
+	ru, ok := ps.SysUsage().(*syscall.Rusage)
+	if !ok {
+		return 0
+	}
+	// Darwin reports bytes; the other systems report kilobytes.
+	if runtime.GOOS == "darwin" || runtime.GOOS == "ios" {
+		return int64(ru.Maxrss)
+	}
+	return int64(ru.Maxrss) * 1024
+}
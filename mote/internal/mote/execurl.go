@@ -0,0 +1,123 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mote
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// The exec transport reaches servers that mote knows nothing about,
+// by running a command line whose standard input and output are
+// connected to a mote server somewhere: "exec://kubectl exec -i pod --
+// mote serve -", "exec://docker exec -i box mote serve -", or a wrapper
+// around a serial console. The ssh transport is this, with a fixed
+// command line.
+//
+// The URL is the scheme followed by the command line, which is split
+// into words at spaces, as by a shell with quoting but no expansions.
+// It is not a URL that url.Parse accepts; it travels, and sits in an
+// alias's URL in config.json, as is.
+//
+// Some channels, such as adb shell and serial consoles, are not binary
+// safe. For those, the exec+hex:// scheme runs the same way but speaks
+// hex (see hex.go): the command line must start "mote serve -hex-",
+// not "mote serve -", on the far end.
+
+const (
+	execScheme    = "exec://"
+	execHexScheme = "exec+hex://"
+)
+
+// isExecURL reports whether rawURL is an exec:// or exec+hex:// URL.
+func isExecURL(rawURL string) bool {
+	return strings.HasPrefix(rawURL, execScheme) || strings.HasPrefix(rawURL, execHexScheme)
+}
+
+// dialExec connects to a server by running the command line in the
+// exec:// or exec+hex:// URL rawURL.
+// Standard error from the command is hidden unless an error happens.
+func dialExec(rawURL string) (io.ReadWriteCloser, error) {
+	line, hex := strings.CutPrefix(rawURL, execHexScheme)
+	if !hex {
+		line = strings.TrimPrefix(rawURL, execScheme)
+	}
+	args, err := splitCommand(line)
+	if err != nil {
+		return nil, fmt.Errorf("invalid server URL %s: %v", rawURL, err)
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("invalid server URL %s: no command", rawURL)
+	}
+	c := exec.Command(args[0], args[1:]...)
+	c.Stderr = new(bytes.Buffer)
+	p, err := startProcConn(c)
+	if err != nil {
+		return nil, err
+	}
+	if !hex {
+		return p, nil
+	}
+	if err := scanHexHandshake(p); err != nil {
+		return nil, p.abort(err)
+	}
+	return newHexConn(p), nil
+}
+
+// splitCommand splits a command line into words at unquoted spaces and
+// tabs. Single quotes keep everything up to the next single quote in
+// the word; double quotes do the same, except that a backslash in them
+// escapes the next character, as does a backslash outside quotes.
+func splitCommand(line string) ([]string, error) {
+	var args []string
+	var word strings.Builder
+	inWord := false
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; c {
+		case ' ', '\t':
+			if inWord {
+				args = append(args, word.String())
+				word.Reset()
+				inWord = false
+			}
+		case '\'':
+			j := strings.IndexByte(line[i+1:], '\'')
+			if j < 0 {
+				return nil, fmt.Errorf("unterminated ' in command")
+			}
+			word.WriteString(line[i+1 : i+1+j])
+			i += 1 + j
+			inWord = true
+		case '"':
+			i++
+			for ; i < len(line) && line[i] != '"'; i++ {
+				if line[i] == '\\' && i+1 < len(line) {
+					i++
+				}
+				word.WriteByte(line[i])
+			}
+			if i == len(line) {
+				return nil, fmt.Errorf("unterminated \" in command")
+			}
+			inWord = true
+		case '\\':
+			if i+1 < len(line) {
+				i++
+			}
+			word.WriteByte(line[i])
+			inWord = true
+		default:
+			word.WriteByte(c)
+			inWord = true
+		}
+	}
+	if inWord {
+		args = append(args, word.String())
+	}
+	return args, nil
+}
@@ -0,0 +1,32 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mote
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSplitCommand(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want []string
+		err  bool
+	}{
+		{"", nil, false},
+		{"  mote  serve -  ", []string{"mote", "serve", "-"}, false},
+		{"kubectl exec -i pod -- mote serve -", []string{"kubectl", "exec", "-i", "pod", "--", "mote", "serve", "-"}, false},
+		{`sh -c 'exec mote serve -'`, []string{"sh", "-c", "exec mote serve -"}, false},
+		{`a "b \"c\" d" e\ f`, []string{"a", `b "c" d`, "e f"}, false},
+		{`a''b ""`, []string{"ab", ""}, false},
+		{`a 'b`, nil, true},
+		{`a "b`, nil, true},
+	} {
+		got, err := splitCommand(tt.in)
+		if (err != nil) != tt.err || !slices.Equal(got, tt.want) {
+			t.Errorf("splitCommand(%q) = %q, %v, want %q, err=%v", tt.in, got, err, tt.want, tt.err)
+		}
+	}
+}
@@ -0,0 +1,361 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mote
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Port forwarding.
+//
+// While a command runs, the client and the server carry TCP connections
+// for each other, like ssh -L and -R, so that a test on the server can
+// reach a stand-in service on the client (-R) and the client can reach
+// a service the command starts on the server (-L). Each connection is a
+// channel in the session: Open, Data, and Close packets that carry a
+// channel number and travel in both directions, interleaved with the
+// command's own traffic. The end that accepted the connection sends
+// Open, naming where the other end should connect it; the client
+// numbers its channels with odd numbers and the server with even ones,
+// so that the two never collide. See ../../protocol.md.
+//
+// A connection's data waits in an inputQueue at the receiving end until
+// the connection takes it, so that a slow reader never holds up the
+// rest of the session. The queue is bounded: the protocol has no flow
+// control, so a connection that falls forwardQueueLimit bytes behind
+// is failed, as though it had broken, rather than left to fill the
+// receiving end's memory.
+
+// A Forward is a port to forward while a command runs.
+type Forward struct {
+	Remote bool   // listen on the server (-R) rather than the client (-L)
+	Listen string // port to listen on, on the loopback interface
+	Dial   string // host:port the other end connects each connection to
+}
+
+// forwardDialTimeout bounds the connection to a forwarding destination.
+const forwardDialTimeout = 30 * time.Second
+
+// forwardQueueLimit is how much of a forwarded connection's data may
+// wait for the connection to take it before the channel is failed.
+// It is a variable for testing.
+var forwardQueueLimit = 16 << 20
+
+// parseForward parses the port:host:hostport argument of -L or -R.
+func parseForward(remote bool, s string) (*Forward, error) {
+	port, dial, _ := strings.Cut(s, ":")
+	if n, err := strconv.Atoi(port); err != nil || n <= 0 || n > 65535 {
+		return nil, fmt.Errorf("invalid forward %q: want port:host:hostport", s)
+	}
+	if _, _, err := net.SplitHostPort(dial); err != nil {
+		return nil, fmt.Errorf("invalid forward %q: want port:host:hostport", s)
+	}
+	return &Forward{Remote: remote, Listen: port, Dial: dial}, nil
+}
+
+// A forwardFlag is the -L or -R flag, adding to forwards.
+type forwardFlag struct {
+	remote bool
+}
+
+var forwards []*Forward
+
+func (f forwardFlag) String() string { return "" }
+
+func (f forwardFlag) Set(s string) error {
+	fw, err := parseForward(f.remote, s)
+	if err != nil {
+		return err
+	}
+	forwards = append(forwards, fw)
+	return nil
+}
+
+// A forwarder carries the forwarded connections of one session.
+type forwarder struct {
+	conn   *Conn
+	server bool      // this is the server end of the session
+	stderr io.Writer // where the client reports connections that fail
+
+	// remote maps the server's listening ports to the addresses the
+	// client connects them to. The server connects to what it is sent.
+	remote map[string]string
+
+	mu     sync.Mutex
+	next   int // next channel number
+	chans  map[int]*channel
+	lns    map[net.Listener]string // listeners and the addresses they forward to
+	closed bool
+}
+
+// A channel is one forwarded connection.
+type channel struct {
+	addr string      // the forwarding destination, for error messages
+	c    net.Conn    // the local connection (nil while it is being dialed)
+	in   *inputQueue // data from the other end, waiting for c
+	done int         // directions finished; at 2 the channel is over
+}
+
+// newForwarder returns a forwarder for the client or server end of the
+// session on conn. The client reports failed connections to stderr.
+func newForwarder(conn *Conn, server bool, stderr io.Writer) *forwarder {
+	f := &forwarder{
+		conn:   conn,
+		server: server,
+		stderr: stderr,
+		remote: make(map[string]string),
+		chans:  make(map[int]*channel),
+		lns:    make(map[net.Listener]string),
+		next:   1,
+	}
+	if f.server {
+		f.next = 2
+	}
+	return f
+}
+
+// send sends a channel packet to the other end, as a Request from the
+// client or a Response from the server. A Response's Error is fatal to
+// the session, so the server reports a failed channel in Status.
+func (f *forwarder) send(typ string, id int, addr, errText string, data []byte) {
+	f.mu.Lock()
+	closed := f.closed
+	f.mu.Unlock()
+	if closed {
+		return
+	}
+	if f.server {
+		f.conn.writePacket(&Response{Type: typ, Chan: id, Addr: addr, Status: errText}, data)
+	} else {
+		f.conn.writePacket(&Request{Type: typ, Chan: id, Addr: addr, Error: errText}, data)
+	}
+}
+
+// report tells the user on the client that a forwarded connection failed.
+func (f *forwarder) report(addr, errText string) {
+	if f.stderr != nil {
+		fmt.Fprintf(f.stderr, "mote: forwarding to %s: %s\n", addr, errText)
+	}
+}
+
+// listen listens on port on the loopback interface for connections
+// for the other end to connect to addr. Connections wait until start.
+func (f *forwarder) listen(port, addr string) error {
+	ln, err := net.Listen("tcp", net.JoinHostPort("localhost", port))
+	if err != nil {
+		return fmt.Errorf("forwarding: %v", err)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.lns[ln] = addr
+	return nil
+}
+
+// start starts accepting connections on the listeners.
+func (f *forwarder) start() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for ln, addr := range f.lns {
+		go f.accept(ln, addr)
+	}
+}
+
+// accept forwards the connections accepted on ln to addr at the other
+// end, until ln is closed.
+func (f *forwarder) accept(ln net.Listener, addr string) {
+	for {
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		f.mu.Lock()
+		if f.closed {
+			f.mu.Unlock()
+			c.Close()
+			return
+		}
+		id := f.next
+		f.next += 2
+		ch := &channel{addr: addr, c: c, in: newInputQueue(forwardQueueLimit)}
+		f.chans[id] = ch
+		f.mu.Unlock()
+		f.send("Open", id, addr, "", nil)
+		f.run(id, ch)
+	}
+}
+
+// open handles an Open packet: the other end accepted connection id,
+// to be connected to addr at this end.
+func (f *forwarder) open(id int, addr string) {
+	if !f.server {
+		port := addr
+		if addr = f.remote[port]; addr == "" {
+			f.send("Close", id, "", fmt.Sprintf("no forward for port %s", port), nil)
+			return
+		}
+	}
+	f.mu.Lock()
+	if f.closed || f.chans[id] != nil {
+		f.mu.Unlock()
+		return
+	}
+	ch := &channel{addr: addr, in: newInputQueue(forwardQueueLimit)}
+	f.chans[id] = ch
+	f.mu.Unlock()
+
+	// Data for the connection can arrive while it is being dialed;
+	// it waits in ch.in.
+	go func() {
+		c, err := net.DialTimeout("tcp", addr, forwardDialTimeout)
+		f.mu.Lock()
+		if f.closed || f.chans[id] != ch {
+			// shutdown, close, or fail has already closed ch.in.
+			f.mu.Unlock()
+			if c != nil {
+				c.Close()
+			}
+			return
+		}
+		if err != nil {
+			delete(f.chans, id)
+			f.mu.Unlock()
+			ch.in.close()
+			f.report(addr, err.Error())
+			f.send("Close", id, "", err.Error(), nil)
+			return
+		}
+		ch.c = c
+		f.mu.Unlock()
+		f.run(id, ch)
+	}()
+}
+
+// data handles a Data packet, queuing the data for connection id.
+func (f *forwarder) data(id int, data []byte) {
+	f.mu.Lock()
+	ch := f.chans[id]
+	f.mu.Unlock()
+	if ch != nil && !ch.in.add(data) {
+		f.fail(id, ch, fmt.Sprintf("connection not reading: more than %d bytes waiting", forwardQueueLimit))
+	}
+}
+
+// fail ends connection id, reporting errText to the user and to the
+// other end, which closes its own connection.
+func (f *forwarder) fail(id int, ch *channel, errText string) {
+	f.mu.Lock()
+	if f.chans[id] != ch {
+		f.mu.Unlock()
+		return
+	}
+	delete(f.chans, id)
+	c := ch.c
+	f.mu.Unlock()
+	f.report(ch.addr, errText)
+	f.send("Close", id, "", errText, nil)
+	ch.in.close()
+	if c != nil {
+		c.Close()
+	}
+}
+
+// close handles a Close packet: the other end has no more data for
+// connection id, or, if errText is set, could not connect it at all.
+func (f *forwarder) close(id int, errText string) {
+	f.mu.Lock()
+	ch := f.chans[id]
+	if ch != nil && errText != "" {
+		delete(f.chans, id)
+	}
+	f.mu.Unlock()
+	if ch == nil {
+		return
+	}
+	ch.in.close()
+	if errText != "" {
+		f.report(ch.addr, errText)
+		if ch.c != nil {
+			ch.c.Close()
+		}
+	}
+}
+
+// run copies data for connection id between its local connection and
+// the other end, in both directions, until both are done.
+func (f *forwarder) run(id int, ch *channel) {
+	go func() {
+		ch.in.copyTo(closeWriter{ch.c})
+		f.finish(id)
+	}()
+	go func() {
+		buf := make([]byte, 32<<10)
+		for {
+			n, err := ch.c.Read(buf)
+			if n > 0 {
+				f.send("Data", id, "", "", buf[:n])
+			}
+			if err != nil {
+				f.send("Close", id, "", "", nil)
+				f.finish(id)
+				return
+			}
+		}
+	}()
+}
+
+// finish records that one direction of connection id is done,
+// closing the connection when both are.
+func (f *forwarder) finish(id int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	ch := f.chans[id]
+	if ch == nil {
+		return
+	}
+	if ch.done++; ch.done == 2 {
+		delete(f.chans, id)
+		ch.c.Close()
+	}
+}
+
+// shutdown stops forwarding: it closes the listeners and every
+// connection, when the command exits.
+func (f *forwarder) shutdown() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return
+	}
+	f.closed = true
+	for ln := range f.lns {
+		ln.Close()
+	}
+	for id, ch := range f.chans {
+		delete(f.chans, id)
+		ch.in.close()
+		if ch.c != nil {
+			ch.c.Close()
+		}
+	}
+}
+
+// A closeWriter closes only the write side of a connection that can,
+// so that the far end sees EOF but can still send its reply.
+type closeWriter struct {
+	net.Conn
+}
+
+func (c closeWriter) Close() error {
+	if cw, ok := c.Conn.(interface{ CloseWrite() error }); ok {
+		return cw.CloseWrite()
+	}
+	return c.Conn.Close()
+}
@@ -0,0 +1,266 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mote
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fwdCheckMain is the fwdcheck command run by the forwarding tests:
+// it sends "ping" to the address in its argument and prints the reply.
+func fwdCheckMain() {
+	log.SetPrefix("fwdcheck: ")
+	log.SetFlags(0)
+	if len(os.Args) != 2 {
+		log.Fatal("usage: fwdcheck host:port")
+	}
+	c, err := net.Dial("tcp", os.Args[1])
+	if err != nil {
+		log.Fatal(err)
+	}
+	pingPong(c)
+	os.Exit(0)
+}
+
+// pingPong sends "ping" on c, closes c for writing, and copies the
+// reply to standard output.
+func pingPong(c net.Conn) {
+	c.Write([]byte("ping"))
+	c.(*net.TCPConn).CloseWrite()
+	io.Copy(os.Stdout, c)
+	c.Close()
+}
+
+// pongServer starts a server that answers everything it reads with
+// "pong: " and what it read, returning its address.
+func pongServer(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				data, _ := io.ReadAll(c)
+				fmt.Fprintf(c, "pong: %s", data)
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+// freePort returns a port that was free a moment ago.
+func freePort(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	return fmt.Sprint(ln.Addr().(*net.TCPAddr).Port)
+}
+
+func TestForwardRemote(t *testing.T) {
+	// -R: the command connects to a port on the server
+	// and reaches a service on the client.
+	setupDirs(t)
+	mockPATH(t, "fwdcheck")
+	port := freePort(t)
+	var outb, errb bytes.Buffer
+	w, err := startServeClient(t, "").Run(t.Context(), &Exec{
+		Args:     []string{"fwdcheck", "localhost:" + port},
+		Dir:      "/mote-test",
+		Forwards: []*Forward{{Remote: true, Listen: port, Dial: pongServer(t)}},
+		Stdout:   &outb,
+		Stderr:   &errb,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != 0 || outb.String() != "pong: ping" {
+		t.Errorf("code=%d stdout=%q stderr=%q; want 0, %q", w.Code, outb.String(), errb.String(), "pong: ping")
+	}
+}
+
+func TestForwardLocal(t *testing.T) {
+	// -L: the client connects to a port on the client
+	// and reaches a service on the server, while the command runs.
+	setupDirs(t)
+	port := freePort(t)
+	stdin, stdinw := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		_, err := startServeClient(t, "").Run(t.Context(), &Exec{
+			Args:     []string{"cat"},
+			Dir:      "/mote-test",
+			Stdin:    stdin,
+			Forwards: []*Forward{{Listen: port, Dial: pongServer(t)}},
+		})
+		done <- err
+	}()
+
+	var c net.Conn
+	for deadline := time.Now().Add(10 * time.Second); ; {
+		var err error
+		if c, err = net.Dial("tcp", "localhost:"+port); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.Write([]byte("ping"))
+	c.(*net.TCPConn).CloseWrite()
+	reply, err := io.ReadAll(c)
+	c.Close()
+	if err != nil || string(reply) != "pong: ping" {
+		t.Errorf("reply = %q, %v; want %q", reply, err, "pong: ping")
+	}
+
+	stdinw.Close() // cat exits, ending the forwarding
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if c, err := net.Dial("tcp", "localhost:"+port); err == nil {
+		c.Close()
+		t.Errorf("port still forwarded after the command exited")
+	}
+}
+
+func TestForwardDialError(t *testing.T) {
+	// A forward to a closed port reports the failure and closes the
+	// connection, without disturbing the command.
+	setupDirs(t)
+	mockPATH(t, "fwdcheck")
+	port := freePort(t)
+	var outb, errb bytes.Buffer
+	w, err := startServeClient(t, "").Run(t.Context(), &Exec{
+		Args:     []string{"fwdcheck", "localhost:" + port},
+		Dir:      "/mote-test",
+		Forwards: []*Forward{{Remote: true, Listen: port, Dial: "localhost:" + freePort(t)}},
+		Stdout:   &outb,
+		Stderr:   &errb,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != 0 || outb.String() != "" || !bytes.Contains(errb.Bytes(), []byte("mote: forwarding to localhost:")) {
+		t.Errorf("code=%d stdout=%q stderr=%q; want 0, no output, forwarding error", w.Code, outb.String(), errb.String())
+	}
+}
+
+func TestForwardQueueLimit(t *testing.T) {
+	// A connection that stops reading fails once too much data is
+	// waiting for it, instead of queuing without bound.
+	setupDirs(t)
+	defer func(n int) { forwardQueueLimit = n }(forwardQueueLimit)
+	forwardQueueLimit = 64 << 10
+
+	// A service that accepts connections and never reads them.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer c.Close()
+		}
+	}()
+
+	port := freePort(t)
+	stdin, stdinw := io.Pipe()
+	var errb bytes.Buffer
+	done := make(chan error, 1)
+	go func() {
+		_, err := startServeClient(t, "").Run(t.Context(), &Exec{
+			Args:     []string{"cat"},
+			Dir:      "/mote-test",
+			Stdin:    stdin,
+			Forwards: []*Forward{{Listen: port, Dial: ln.Addr().String()}},
+			Stderr:   &errb,
+		})
+		done <- err
+	}()
+	defer func() {
+		stdinw.Close()
+		if err := <-done; err != nil {
+			t.Error(err)
+		}
+		if !strings.Contains(errb.String(), "connection not reading") {
+			t.Errorf("stderr = %q, want report of connection not reading", errb.String())
+		}
+	}()
+
+	var c net.Conn
+	for deadline := time.Now().Add(10 * time.Second); ; {
+		if c, err = net.Dial("tcp", "localhost:"+port); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	defer c.Close()
+	// Write until the failed channel closes the connection.
+	c.SetDeadline(time.Now().Add(30 * time.Second))
+	buf := make([]byte, 32<<10)
+	for {
+		if _, err := c.Write(buf); err != nil {
+			if os.IsTimeout(err) {
+				t.Fatal("connection still open after writing far more than the limit")
+			}
+			break
+		}
+	}
+}
+
+func TestParseForward(t *testing.T) {
+	tests := []struct {
+		in   string
+		want *Forward
+	}{
+		{"8080:localhost:80", &Forward{Listen: "8080", Dial: "localhost:80"}},
+		{"5432:[::1]:5432", &Forward{Listen: "5432", Dial: "[::1]:5432"}},
+		{"8080", nil},
+		{"8080:localhost", nil},
+		{"x:localhost:80", nil},
+		{"0:localhost:80", nil},
+		{"70000:localhost:80", nil},
+	}
+	for _, tt := range tests {
+		fw, err := parseForward(false, tt.in)
+		if tt.want == nil {
+			if err == nil {
+				t.Errorf("parseForward(%q) = %+v, want error", tt.in, *fw)
+			}
+			continue
+		}
+		if err != nil || *fw != *tt.want {
+			t.Errorf("parseForward(%q) = %+v, %v; want %+v", tt.in, fw, err, *tt.want)
+		}
+	}
+}
@@ -2,7 +2,7 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-package main
+package mote
 
 import (
 	"bytes"
@@ -298,12 +298,12 @@ func buildMote(goos, goarch string) (string, error) {
 	c := exec.Command("go", "build", "-o", bin, "rsc.io/cmd/mote")
 	// If this program's source directory exists on this machine,
 	// build there instead, to pick up any local changes being tested.
+	// This file is in internal/mote, two directories down.
 	if _, file, _, ok := runtime.Caller(0); ok {
-		if src := filepath.Dir(file); src != "" {
-			if _, err := os.Stat(filepath.Join(src, "gomote.go")); err == nil {
-				c = exec.Command("go", "build", "-o", bin)
-				c.Dir = src
-			}
+		src := filepath.Join(filepath.Dir(file), "..", "..")
+		if _, err := os.Stat(filepath.Join(src, "internal", "mote", "gomote.go")); err == nil {
+			c = exec.Command("go", "build", "-o", bin)
+			c.Dir = src
 		}
 	}
 	c.Env = append(os.Environ(), "GOOS="+goos, "GOARCH="+goarch, "CGO_ENABLED=0")
@@ -0,0 +1,253 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mote
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// hookName returns the name of the go_$GOOS_$GOARCH_exec hook for
+// goos and goarch, as a file: on Windows, where a shell script cannot
+// run, the hook is a batch file, which the go command finds by the
+// extensions in %PATHEXT%.
+func hookName(goos, goarch string) string {
+	name := "go_" + goos + "_" + goarch + "_exec"
+	if runtime.GOOS == "windows" {
+		name += ".cmd"
+	}
+	return name
+}
+
+// hookScript is the contents of a hook that mote installs.
+func hookScript() string {
+	if runtime.GOOS == "windows" {
+		return "@mote -t %*\r\n"
+	}
+	return "#!/bin/sh\nexec mote -t \"$@\"\n"
+}
+
+// isMoteHook reports whether the file is a hook as mote installs it.
+func isMoteHook(file string) bool {
+	data, err := os.ReadFile(file)
+	return err == nil && bytes.Equal(data, []byte(hookScript()))
+}
+
+// goTargets returns the GOOS-GOARCH combinations the go command can
+// build for, other than this system's own, as goos/goarch pairs.
+func goTargets() ([][2]string, error) {
+	out, err := exec.Command("go", "tool", "dist", "list").Output()
+	if err != nil {
+		return nil, fmt.Errorf("go tool dist list: %v", err)
+	}
+	var targets [][2]string
+	for line := range strings.Lines(string(out)) {
+		goos, goarch, ok := strings.Cut(strings.TrimSpace(line), "/")
+		if !ok || goos == runtime.GOOS && goarch == runtime.GOARCH {
+			continue
+		}
+		targets = append(targets, [2]string{goos, goarch})
+	}
+	return targets, nil
+}
+
+// cmdGoSetup implements "mote go-setup [-list | -remove]".
+// With no flags, it installs go_$GOOS_$GOARCH_exec hooks for all
+// GOOS-GOARCH combinations that don't already have one, next to the
+// mote executable, recording them in the configuration so that
+// -remove can remove them again. -list shows, for each combination,
+// its hook and the server its tests would run on.
+func cmdGoSetup(args []string) {
+	flags := flag.NewFlagSet("go-setup", flag.ExitOnError)
+	flags.Usage = usage
+	list := flags.Bool("list", false, "list each target's hook and server")
+	remove := flags.Bool("remove", false, "remove the hooks that go-setup installed")
+	flags.Parse(args)
+	if flags.NArg() != 0 || *list && *remove {
+		usage()
+	}
+	switch {
+	case *list:
+		goSetupList()
+	case *remove:
+		goSetupRemove()
+	default:
+		goSetupInstall()
+	}
+}
+
+// hookDir returns the directory where go-setup installs hooks:
+// the directory holding the mote executable.
+func hookDir() string {
+	exe, err := os.Executable()
+	if err != nil {
+		log.Fatal(err)
+	}
+	return filepath.Dir(exe)
+}
+
+func goSetupInstall() {
+	targets, err := goTargets()
+	if err != nil {
+		log.Fatal(err)
+	}
+	cfg, err := readConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+	dir := hookDir()
+	n := 0
+	for _, t := range targets {
+		name := hookName(t[0], t[1])
+		if _, err := exec.LookPath(name); err == nil {
+			continue
+		}
+		file := filepath.Join(dir, name)
+		if err := os.WriteFile(file, []byte(hookScript()), 0o777); err != nil {
+			log.Fatal(err)
+		}
+		if !slices.Contains(cfg.Hooks, file) {
+			cfg.Hooks = append(cfg.Hooks, file)
+		}
+		n++
+	}
+	if n > 0 {
+		if err := writeConfig(cfg); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if n == 0 {
+		fmt.Printf("no hooks left to install\n")
+	} else {
+		fmt.Printf("installed %d hooks in %s\n", n, dir)
+	}
+}
+
+// goSetupRemove removes the hooks that go-setup installed: those
+// recorded in the configuration and, from before go-setup recorded
+// them, any next to the mote executable that are exactly as go-setup
+// writes them. A recorded hook that has since been edited is left
+// alone, and stays recorded, so that -list and a later -remove still
+// know it as go-setup's.
+func goSetupRemove() {
+	cfg, err := readConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+	files := cfg.Hooks
+	if targets, err := goTargets(); err == nil {
+		dir := hookDir()
+		for _, t := range targets {
+			file := filepath.Join(dir, hookName(t[0], t[1]))
+			if !slices.Contains(files, file) && isMoteHook(file) {
+				files = append(files, file)
+			}
+		}
+	}
+	n := 0
+	var kept []string // recorded hooks left in place
+	for _, file := range files {
+		if _, err := os.Stat(file); os.IsNotExist(err) {
+			continue
+		}
+		recorded := slices.Contains(cfg.Hooks, file)
+		if !isMoteHook(file) {
+			log.Printf("leaving %s: not as go-setup wrote it", file)
+			if recorded {
+				kept = append(kept, file)
+			}
+			continue
+		}
+		if err := os.Remove(file); err != nil {
+			log.Print(err)
+			if recorded {
+				kept = append(kept, file)
+			}
+			continue
+		}
+		n++
+	}
+	if !slices.Equal(cfg.Hooks, kept) {
+		cfg.Hooks = kept
+		if err := writeConfig(cfg); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if n == 0 {
+		fmt.Printf("no hooks to remove\n")
+	} else {
+		fmt.Printf("removed %d hooks\n", n)
+	}
+}
+
+// goSetupList prints, for each GOOS-GOARCH combination, the hook the
+// go command would run and the server mote would choose for it.
+func goSetupList() {
+	targets, err := goTargets()
+	if err != nil {
+		log.Fatal(err)
+	}
+	cfg, err := readConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+	_, gomoteErr := exec.LookPath("gomote")
+	var rows [][3]string
+	for _, t := range targets {
+		goos, goarch := t[0], t[1]
+		hook := "no hook"
+		if file, err := exec.LookPath(hookName(goos, goarch)); err == nil {
+			hook = file
+			if abs, err := filepath.Abs(file); err == nil {
+				file = abs
+			}
+			if slices.Contains(cfg.Hooks, file) || isMoteHook(file) {
+				hook += " (mote)"
+			}
+		}
+		rows = append(rows, [3]string{goos + "/" + goarch, hook, targetServer(cfg, goos, goarch, gomoteErr == nil)})
+	}
+	var w [2]int
+	for _, r := range rows {
+		w[0] = max(w[0], len(r[0]))
+		w[1] = max(w[1], len(r[1]))
+	}
+	for _, r := range rows {
+		fmt.Printf("%-*s %-*s %s\n", w[0], r[0], w[1], r[1], r[2])
+	}
+}
+
+// targetServer describes the server that a hook runs goos-goarch tests
+// on when nothing else names one, following resolveAliases: the alias
+// or group of that name, a gomote if the gomote command is installed,
+// or local emulation, if it is possible.
+func targetServer(cfg *config, goos, goarch string, haveGomote bool) string {
+	name := goos + "-" + goarch
+	if a := cfg.Aliases[name]; a != nil {
+		return "alias " + name + " = " + a.URL
+	}
+	if list := cfg.lookup(name); len(list) > 0 {
+		var urls []string
+		for _, a := range list {
+			urls = append(urls, a.URL)
+		}
+		return "group " + name + " = " + strings.Join(urls, " ")
+	}
+	if haveGomote {
+		return "a new gomote"
+	}
+	if qemuFallback(goos, goarch) {
+		return "qemu://" + name
+	}
+	return "no server"
+}
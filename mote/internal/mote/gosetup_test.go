@@ -0,0 +1,112 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mote
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestGoSetup(t *testing.T) {
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("no go command")
+	}
+	setupDirs(t)
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Dir(exe)
+	// Use a minimal PATH holding only the hook directory and the go
+	// command, so that hooks installed for real (outside the test)
+	// cannot make combinations look already covered.
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+filepath.Dir(goTool))
+
+	cmdGoSetup(nil)
+
+	m, err := filepath.Glob(filepath.Join(dir, "go_*_exec"+filepath.Ext(hookName("linux", "amd64"))))
+	if err != nil || len(m) == 0 {
+		t.Fatalf("no hooks installed: %v, %v", m, err)
+	}
+	host := filepath.Join(dir, hookName(runtime.GOOS, runtime.GOARCH))
+	for _, f := range m {
+		if f == host {
+			t.Errorf("installed hook for host GOOS-GOARCH")
+		}
+		data, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != hookScript() {
+			t.Errorf("%s: wrong contents %q", f, data)
+		}
+		if !strings.HasPrefix(filepath.Base(f), "go_") {
+			t.Errorf("bad hook name %s", f)
+		}
+	}
+
+	cfg, err := readConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(cfg.Hooks)
+	if !slices.Equal(cfg.Hooks, m) {
+		t.Errorf("recorded hooks %v, want %v", cfg.Hooks, m)
+	}
+
+	// -remove removes the hooks, except one that has been edited.
+	edited := m[0]
+	if err := os.WriteFile(edited, []byte("#!/bin/sh\nexec mote -t -v \"$@\"\n"), 0o777); err != nil {
+		t.Fatal(err)
+	}
+	cmdGoSetup([]string{"-remove"})
+	for _, f := range m {
+		_, err := os.Stat(f)
+		if f == edited && err != nil {
+			t.Errorf("edited hook %s removed", f)
+		}
+		if f != edited && err == nil {
+			t.Errorf("hook %s not removed", f)
+		}
+	}
+	// The edited hook stays recorded, until it is gone.
+	if cfg, err := readConfig(); err != nil || !slices.Equal(cfg.Hooks, []string{edited}) {
+		t.Errorf("after -remove, recorded hooks %v, %v; want %v", cfg.Hooks, err, []string{edited})
+	}
+	os.Remove(edited)
+	cmdGoSetup([]string{"-remove"})
+	if cfg, err := readConfig(); err != nil || len(cfg.Hooks) != 0 {
+		t.Errorf("after second -remove, recorded hooks %v, %v; want none", cfg.Hooks, err)
+	}
+}
+
+func TestTargetServer(t *testing.T) {
+	cfg := &config{Aliases: map[string]*alias{
+		"linux-arm64": {URL: "ssh://pi"},
+		"s1":          {URL: "tail://s1", Group: "linux-ppc64le"},
+		"s2":          {URL: "tail://s2", Group: "linux-ppc64le"},
+	}}
+	tests := []struct {
+		goos, goarch string
+		gomote       bool
+		want         string
+	}{
+		{"linux", "arm64", true, "alias linux-arm64 = ssh://pi"},
+		{"linux", "ppc64le", false, "group linux-ppc64le = tail://s1 tail://s2"},
+		{"plan9", "386", true, "a new gomote"},
+		{"plan9", "386", false, "no server"},
+	}
+	for _, tt := range tests {
+		if got := targetServer(cfg, tt.goos, tt.goarch, tt.gomote); got != tt.want {
+			t.Errorf("targetServer(%s, %s, %v) = %q, want %q", tt.goos, tt.goarch, tt.gomote, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,275 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mote
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// Testing from source.
+//
+// "mote go test [pkgs]", run inside a Go module, runs go test on the
+// server with the server's own toolchain, instead of running a test
+// binary built on the client. It uploads the module's source tree and
+// whatever the build needs from outside it: nothing more if the module
+// vendors its dependencies, and otherwise the replacement directories
+// and the downloaded module files in the client's module cache, which
+// the server's go command reads as a GOPROXY (file:// URLs are a proxy
+// too), with GOFLAGS=-mod=mod. The server's own module cache fills
+// from that proxy, so it never needs the network. Output streams back
+// as for any other command.
+
+// goTestExclude lists what is never uploaded from a module tree,
+// in addition to the alias's Exclude patterns.
+var goTestExclude = []string{".git", ".hg", ".svn"}
+
+// isGoTest reports whether args is a go test command that mote runs
+// from source: one run in a Go module, whose root it returns.
+// Outside a module, "mote go test" runs the server's go command as is.
+func isGoTest(args []string, dir string) (string, bool) {
+	if len(args) < 2 || args[0] != "go" || args[1] != "test" {
+		return "", false
+	}
+	root := modRoot(dir)
+	return root, root != ""
+}
+
+// modRoot returns the root of the Go module containing dir,
+// or "" if there is none.
+func modRoot(dir string) string {
+	for {
+		if isRegular(filepath.Join(dir, "go.mod")) {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// A goModule is a module listed by "go list -m -json".
+type goModule struct {
+	Path    string
+	Version string
+	Main    bool
+	Dir     string
+	Replace *goModule
+}
+
+// goTestExec returns the Exec for running args, a go test command,
+// from the client directory dir inside the module at root, on conn's
+// server with alias settings a.
+func goTestExec(conn *Conn, args []string, dir, root string, a *alias) (*Exec, error) {
+	exclude := append(goTestExclude[:len(goTestExclude):len(goTestExclude)], a.Exclude...)
+	var files []*File
+	if err := addTree(&files, root, exclude); err != nil {
+		return nil, err
+	}
+	for _, p := range uploads {
+		if err := addTree(&files, p, a.Exclude); err != nil {
+			return nil, err
+		}
+	}
+	// Use the toolchain the server has, and only the module uploaded.
+	env := []string{"GOTOOLCHAIN=local", "GOWORK=off"}
+	var pathEnv []string
+	if !isRegular(filepath.Join(root, "vendor", "modules.txt")) {
+		mods, err := goListModules(root)
+		if err != nil {
+			return nil, err
+		}
+		graph, err := goModGraph(root)
+		if err != nil {
+			return nil, err
+		}
+		cache, err := goModCache(root)
+		if err != nil {
+			return nil, err
+		}
+		proxy, err := proxyFiles(filepath.Join(cache, "cache", "download"), mods, graph)
+		if err != nil {
+			return nil, err
+		}
+		if len(proxy) > 0 {
+			if !conn.has(capPathEnv) {
+				return nil, fmt.Errorf("server too old to run go test from source with module dependencies")
+			}
+			for _, name := range proxy {
+				if err := addFile(&files, name); err != nil {
+					return nil, err
+				}
+			}
+			proxyDir := filepath.ToSlash(filepath.Join(cache, "cache", "download"))
+			pathEnv = append(pathEnv, "GOPROXY=file://"+proxyDir)
+		}
+		for _, m := range mods {
+			if r := m.Replace; r != nil && r.Version == "" && r.Dir != "" {
+				if err := addTree(&files, r.Dir, exclude); err != nil {
+					return nil, err
+				}
+			}
+		}
+		env = append(env, "GOFLAGS=-mod=mod", "GOSUMDB=off")
+	}
+	e := runExec(args, dir, files, a)
+	// The alias's settings come last, so that they win.
+	e.Env = append(env, a.Env...)
+	e.PathEnv = pathEnv
+	return e, nil
+}
+
+// goListModules returns the modules in the build list of the module
+// at root.
+func goListModules(root string) ([]*goModule, error) {
+	c := exec.Command("go", "list", "-m", "-json", "all")
+	c.Dir = root
+	c.Env = append(os.Environ(), "GOWORK=off")
+	var stderr bytes.Buffer
+	c.Stderr = &stderr
+	out, err := c.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go list -m all: %v\n%s", err, stderr.Bytes())
+	}
+	var mods []*goModule
+	dec := json.NewDecoder(bytes.NewReader(out))
+	for {
+		m := new(goModule)
+		if err := dec.Decode(m); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("go list -m all: %v", err)
+		}
+		mods = append(mods, m)
+	}
+	return mods, nil
+}
+
+// goModGraph returns the module versions in the module graph of the
+// module at root, other than the main module, as "go mod graph" lists
+// them: with their original paths, not their replacements.
+func goModGraph(root string) ([]*goModule, error) {
+	c := exec.Command("go", "mod", "graph")
+	c.Dir = root
+	c.Env = append(os.Environ(), "GOWORK=off")
+	var stderr bytes.Buffer
+	c.Stderr = &stderr
+	out, err := c.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go mod graph: %v\n%s", err, stderr.Bytes())
+	}
+	var mods []*goModule
+	seen := make(map[string]bool)
+	for _, f := range strings.Fields(string(out)) {
+		path, vers, ok := strings.Cut(f, "@")
+		// The main module has no version, and go and toolchain
+		// are not modules in the cache.
+		if !ok || path == "go" || path == "toolchain" || seen[f] {
+			continue
+		}
+		seen[f] = true
+		mods = append(mods, &goModule{Path: path, Version: vers})
+	}
+	return mods, nil
+}
+
+// goModCache returns the client's module cache directory.
+func goModCache(root string) (string, error) {
+	c := exec.Command("go", "env", "GOMODCACHE")
+	c.Dir = root
+	out, err := c.Output()
+	if err != nil {
+		return "", fmt.Errorf("go env GOMODCACHE: %v", err)
+	}
+	dir := strings.TrimSpace(string(out))
+	if dir == "" {
+		return "", fmt.Errorf("go env GOMODCACHE: no module cache")
+	}
+	return dir, nil
+}
+
+// proxyFiles returns the files in download, the download directory of
+// a module cache, that a go command using it as a GOPROXY needs to
+// build with the modules mods, from the module graph graph: the .info,
+// .mod, and .zip files of each module version in mods, as far as the
+// cache has them, and the .mod file of each other version in graph.
+// The go command downloads only the go.mod of a module that provides
+// no packages, so a missing .zip is not an error; a missing .mod is.
+// Versions that are in the graph but not selected matter when it is
+// not pruned: the go command reads their go.mod files too, to find
+// the requirements of dependencies older than Go 1.17.
+func proxyFiles(download string, mods, graph []*goModule) ([]string, error) {
+	var files []string
+	seen := make(map[string]bool)
+	replaced := make(map[string]bool)
+	for _, m := range mods {
+		if m.Main {
+			continue
+		}
+		seen[m.Path+"@"+m.Version] = true
+		if m.Replace != nil {
+			replaced[m.Path] = true
+			m = m.Replace
+		}
+		if m.Version == "" {
+			continue // a directory, uploaded as it is
+		}
+		base := modCacheBase(download, m)
+		if !isRegular(base + ".mod") {
+			return nil, fmt.Errorf("%s@%s: not in module cache; run go mod download", m.Path, m.Version)
+		}
+		for _, ext := range []string{".info", ".mod", ".zip"} {
+			if isRegular(base + ext) {
+				files = append(files, base+ext)
+			}
+		}
+	}
+	for _, m := range graph {
+		if seen[m.Path+"@"+m.Version] {
+			continue
+		}
+		seen[m.Path+"@"+m.Version] = true
+		mod := modCacheBase(download, m) + ".mod"
+		if !isRegular(mod) {
+			if replaced[m.Path] {
+				continue // its go.mod comes from the replacement
+			}
+			return nil, fmt.Errorf("%s@%s: not in module cache; run go mod download", m.Path, m.Version)
+		}
+		files = append(files, mod)
+	}
+	return files, nil
+}
+
+// modCacheBase returns the name of m's files in download, the download
+// directory of a module cache, without the extension.
+func modCacheBase(download string, m *goModule) string {
+	return filepath.Join(download, filepath.FromSlash(escapeModPath(m.Path)), "@v", escapeModPath(m.Version))
+}
+
+// escapeModPath returns the module path or version s as the module
+// cache and proxy protocol spell it, with each upper-case letter
+// replaced by an exclamation mark and the letter's lower-case form,
+// so that it is safe on case-insensitive file systems.
+func escapeModPath(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if 'A' <= r && r <= 'Z' {
+			b.WriteByte('!')
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
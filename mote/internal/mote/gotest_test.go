@@ -0,0 +1,234 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mote
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestIsGoTest(t *testing.T) {
+	root := t.TempDir()
+	sub := filepath.Join(root, "a", "b")
+	if err := os.MkdirAll(sub, 0o777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "go.mod"), []byte("module m\n"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := isGoTest([]string{"go", "test", "./..."}, sub); !ok || got != root {
+		t.Errorf("isGoTest(go test) in module = %q, %v, want %q, true", got, ok, root)
+	}
+	for _, args := range [][]string{{"go"}, {"go", "version"}, {"./x.test"}} {
+		if _, ok := isGoTest(args, sub); ok {
+			t.Errorf("isGoTest(%q) = true", args)
+		}
+	}
+	if _, ok := isGoTest([]string{"go", "test"}, t.TempDir()); ok {
+		t.Errorf("isGoTest outside module = true")
+	}
+}
+
+func TestPathEnv(t *testing.T) {
+	setupDirs(t)
+	var outb, errb bytes.Buffer
+	w, err := startServeClient(t, "").Run(t.Context(), &Exec{
+		Args:    []string{"sh", "-c", "echo $GOPROXY; echo $MOTE_TEST_DATA"},
+		Dir:     "/mote-test",
+		PathEnv: []string{"GOPROXY=file:///mote-test/proxy", "MOTE_TEST_DATA=/mote-test/data"},
+		Stdout:  &outb,
+		Stderr:  &errb,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(outb.String(), "\n"), "\n")
+	if w.Code != 0 || len(lines) != 2 {
+		t.Fatalf("code=%d stdout=%q stderr=%q; want 0, two lines", w.Code, outb.String(), errb.String())
+	}
+	// The server maps the paths into its copy of the tree.
+	proxy, data := lines[0], lines[1]
+	if !strings.HasPrefix(proxy, "file://") || !strings.HasSuffix(proxy, "/mote-test/proxy") || proxy == "file:///mote-test/proxy" {
+		t.Errorf("GOPROXY=%q, want file:// URL for mapped /mote-test/proxy", proxy)
+	}
+	if !strings.HasSuffix(filepath.ToSlash(data), "/mote-test/data") || data == "/mote-test/data" {
+		t.Errorf("MOTE_TEST_DATA=%q, want mapped /mote-test/data", data)
+	}
+}
+
+func TestEscapeModPath(t *testing.T) {
+	for _, tt := range []struct{ in, want string }{
+		{"golang.org/x/sys", "golang.org/x/sys"},
+		{"github.com/BurntSushi/toml", "github.com/!burnt!sushi/toml"},
+		{"v1.0.0-RC1", "v1.0.0-!r!c1"},
+	} {
+		if got := escapeModPath(tt.in); got != tt.want {
+			t.Errorf("escapeModPath(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestProxyFiles(t *testing.T) {
+	download := t.TempDir()
+	for _, name := range []string{
+		"golang.org/x/sys/@v/v0.1.0.info",
+		"golang.org/x/sys/@v/v0.1.0.mod",
+		"golang.org/x/sys/@v/v0.1.0.zip",
+		"golang.org/x/sys/@v/v0.1.0.ziphash",
+		"golang.org/x/sys/@v/v0.2.0.mod", // another version, not needed
+		"golang.org/x/sys/@v/v0.0.9.info",
+		"golang.org/x/sys/@v/v0.0.9.mod", // in the graph, not selected
+		"golang.org/x/sys/@v/v0.0.9.zip",
+		"github.com/!burnt!sushi/toml/@v/v1.0.0.mod",
+		"example.com/fork/@v/v0.3.0.mod",
+		"example.com/fork/@v/v0.3.0.zip",
+	} {
+		file := filepath.Join(download, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(file), 0o777)
+		if err := os.WriteFile(file, nil, 0o666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mods := []*goModule{
+		{Path: "example.com/m", Main: true},
+		{Path: "golang.org/x/sys", Version: "v0.1.0"},
+		// Only the go.mod of a module providing no packages is downloaded.
+		{Path: "github.com/BurntSushi/toml", Version: "v1.0.0"},
+		{Path: "example.com/orig", Version: "v0.1.0", Replace: &goModule{Path: "example.com/fork", Version: "v0.3.0"}},
+		{Path: "example.com/local", Version: "v0.1.0", Replace: &goModule{Path: "../local", Dir: "/home/gopher/local"}},
+	}
+	graph := []*goModule{
+		{Path: "golang.org/x/sys", Version: "v0.1.0"},
+		{Path: "golang.org/x/sys", Version: "v0.0.9"},
+		// Replaced, so not in the cache and not needed.
+		{Path: "example.com/local", Version: "v0.0.1"},
+	}
+	files, err := proxyFiles(download, mods, graph)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, f := range files {
+		rel, _ := filepath.Rel(download, f)
+		got = append(got, filepath.ToSlash(rel))
+	}
+	want := []string{
+		"golang.org/x/sys/@v/v0.1.0.info",
+		"golang.org/x/sys/@v/v0.1.0.mod",
+		"golang.org/x/sys/@v/v0.1.0.zip",
+		"github.com/!burnt!sushi/toml/@v/v1.0.0.mod",
+		"example.com/fork/@v/v0.3.0.mod",
+		"example.com/fork/@v/v0.3.0.zip",
+		"golang.org/x/sys/@v/v0.0.9.mod",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("proxyFiles:\nhave %q\nwant %q", got, want)
+	}
+
+	_, err = proxyFiles(download, []*goModule{{Path: "golang.org/x/sys", Version: "v0.3.0"}}, nil)
+	if err == nil || !strings.Contains(err.Error(), "not in module cache") {
+		t.Errorf("proxyFiles with missing module: %v, want not in module cache", err)
+	}
+	_, err = proxyFiles(download, nil, []*goModule{{Path: "golang.org/x/sys", Version: "v0.3.0"}})
+	if err == nil || !strings.Contains(err.Error(), "not in module cache") {
+		t.Errorf("proxyFiles with missing graph module: %v, want not in module cache", err)
+	}
+}
+
+// writeProxyModule writes module path at version vers, with the given
+// go.mod and files, to the file:// GOPROXY in dir.
+func writeProxyModule(t *testing.T, dir, path, vers, gomod string, files map[string]string) {
+	t.Helper()
+	base := filepath.Join(dir, filepath.FromSlash(escapeModPath(path)), "@v", vers)
+	if err := os.MkdirAll(filepath.Dir(base), 0o777); err != nil {
+		t.Fatal(err)
+	}
+	var zb bytes.Buffer
+	zw := zip.NewWriter(&zb)
+	files["go.mod"] = gomod
+	for name, data := range files {
+		w, err := zw.Create(path + "@" + vers + "/" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(w, data)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for ext, data := range map[string][]byte{
+		".info": []byte(`{"Version":"` + vers + `"}`),
+		".mod":  []byte(gomod),
+		".zip":  zb.Bytes(),
+	} {
+		if err := os.WriteFile(base+ext, data, 0o666); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestGoTestUnprunedGraph(t *testing.T) {
+	// A module older than Go 1.17 has its module graph loaded in full,
+	// and so the server's go command reads the go.mod of a version
+	// that is not selected: example.com/dep v1.0.0, which
+	// example.com/old requires, when the build uses v1.1.0.
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("no go command")
+	}
+	setupDirs(t)
+	proxy := t.TempDir()
+	writeProxyModule(t, proxy, "example.com/old", "v1.0.0",
+		"module example.com/old\n\nrequire example.com/dep v1.0.0\n",
+		map[string]string{"old.go": "package old\n\nimport \"example.com/dep\"\n\nvar X = dep.X\n"})
+	for _, vers := range []string{"v1.0.0", "v1.1.0"} {
+		writeProxyModule(t, proxy, "example.com/dep", vers,
+			"module example.com/dep\n",
+			map[string]string{"dep.go": "package dep\n\nconst X = 1\n"})
+	}
+	root := t.TempDir()
+	for name, data := range map[string]string{
+		"go.mod":    "module example.com/m\n\ngo 1.16\n\nrequire (\n\texample.com/old v1.0.0\n\texample.com/dep v1.1.0\n)\n",
+		"m_test.go": "package m\n\nimport (\n\t\"testing\"\n\n\t\"example.com/old\"\n)\n\nfunc TestOld(t *testing.T) { t.Log(old.X) }\n",
+	} {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(data), 0o666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The client's module cache fills from the proxy; the server's
+	// starts empty and must fill from what the client uploads.
+	t.Setenv("GOMODCACHE", t.TempDir())
+	t.Setenv("GOPROXY", "file://"+filepath.ToSlash(proxy))
+	t.Setenv("GOFLAGS", "-mod=mod -modcacherw")
+	t.Setenv("GOSUMDB", "off")
+	t.Setenv("GOTOOLCHAIN", "local")
+	download := exec.Command("go", "mod", "download")
+	download.Dir = root
+	if out, err := download.CombinedOutput(); err != nil {
+		t.Fatalf("go mod download: %v\n%s", err, out)
+	}
+
+	conn := startServeClient(t, "")
+	a := &alias{Env: []string{"GOMODCACHE=" + t.TempDir(), "GOFLAGS=-mod=mod -modcacherw"}}
+	e, err := goTestExec(conn, []string{"go", "test", "-v", "."}, root, root, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var outb, errb bytes.Buffer
+	e.Stdout, e.Stderr = &outb, &errb
+	w, err := conn.Run(t.Context(), e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != 0 || !strings.Contains(outb.String(), "--- PASS: TestOld") {
+		t.Fatalf("code=%d stdout=%q stderr=%q; want 0, PASS", w.Code, outb.String(), errb.String())
+	}
+}
@@ -2,7 +2,7 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-package main
+package mote
 
 import (
 	"errors"
@@ -52,7 +52,7 @@ type deadlineConn interface {
 
 // clientHandshake reads the connection preamble and server hello
 // and then sends the client hello.
-// See protocol.md.
+// See ../../protocol.md.
 func clientHandshake(rw io.ReadWriter) error {
 	if err := scanServerHello(rw); err != nil {
 		return err
@@ -146,7 +146,7 @@ func scanHello(r io.Reader, what string, match func(line string) (bool, error))
 }
 
 // serverHandshake sends the server hello and reads the client hello.
-// See protocol.md.
+// See ../../protocol.md.
 func serverHandshake(rw io.ReadWriter) error {
 	if _, err := io.WriteString(rw, serverHello); err != nil {
 		return fmt.Errorf("write server hello: %v", err)
@@ -2,7 +2,7 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-package main
+package mote
 
 import (
 	"bytes"
@@ -27,7 +27,7 @@ import (
 // writes hexHandshake unencoded and encodes everything after it; the
 // client skips whatever the shell printed first, finds that line, and
 // encodes in turn. The protocol above knows nothing about any of this.
-// See protocol.md.
+// See ../../protocol.md.
 
 // hexHandshake is the line marking the start of hex encoding.
 // A terminal may deliver it as "...\r\n" and may print escape
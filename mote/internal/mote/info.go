@@ -0,0 +1,236 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mote
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os/exec"
+	"reflect"
+	"runtime"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/cpu"
+)
+
+// Details describes a server's machine, for mote info.
+// A client asks for them with an Info request in place of Setup,
+// and the server answers with a second Info response carrying them.
+// See ../../protocol.md.
+type Details struct {
+	Mote       string        `json:",omitzero"` // version of the mote server
+	CPU        string        `json:",omitzero"` // CPU model name
+	CPUs       int           `json:",omitzero"` // number of logical CPUs
+	Features   []string      `json:",omitzero"` // CPU feature flags
+	Memory     int64         `json:",omitzero"` // physical memory, in bytes
+	OS         string        `json:",omitzero"` // operating system and kernel version
+	Go         string        `json:",omitzero"` // output of "go version", if the server has a go command
+	CacheFiles int           `json:",omitzero"` // files in the upload cache
+	CacheSize  int64         `json:",omitzero"` // bytes in the upload cache
+	CacheLimit int64         `json:",omitzero"` // maximum bytes in the cache (0 for no limit)
+	CacheAge   time.Duration `json:",omitzero"` // maximum age of an unused cached file
+	Time       time.Time     // the server's clock as it answered
+}
+
+// goVersionTimeout bounds running "go version" on the server.
+const goVersionTimeout = 10 * time.Second
+
+// describe returns the Details of this machine, for a client whose
+// commands run with the environment env: in particular, Go is the
+// go command on env's PATH.
+func describe(env []string) *Details {
+	d := &Details{
+		Mote:     moteVersion(),
+		CPU:      cpuModel(),
+		CPUs:     runtime.NumCPU(),
+		Features: cpuFeatures(),
+		Memory:   memTotal(),
+		OS:       osVersion(),
+	}
+
+	if goCmd, err := lookPathEnv("go", env); err == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), goVersionTimeout)
+		defer cancel()
+		c := exec.CommandContext(ctx, goCmd, "version")
+		c.Env = env
+		if out, err := c.Output(); err == nil {
+			d.Go = strings.TrimSpace(string(out))
+		}
+	}
+
+	entries, _ := cacheEntries() // an unusable cache is an empty one
+	for _, e := range entries {
+		d.CacheFiles++
+		d.CacheSize += e.size
+	}
+	d.CacheAge, d.CacheLimit = cacheLimits()
+	d.Time = time.Now()
+	return d
+}
+
+// cpuFeatures returns the names of the CPU features that x/sys/cpu
+// reports for this machine, like "avx2" and "atomics".
+func cpuFeatures() []string {
+	var v reflect.Value
+	switch runtime.GOARCH {
+	case "amd64", "386":
+		v = reflect.ValueOf(cpu.X86)
+	case "arm64":
+		v = reflect.ValueOf(cpu.ARM64)
+	case "arm":
+		v = reflect.ValueOf(cpu.ARM)
+	default:
+		return nil
+	}
+	var names []string
+	for i := range v.NumField() {
+		name, ok := strings.CutPrefix(v.Type().Field(i).Name, "Has")
+		if ok && v.Field(i).Kind() == reflect.Bool && v.Field(i).Bool() {
+			names = append(names, strings.ToLower(name))
+		}
+	}
+	return names
+}
+
+// describe asks the server at the other end of c for its Details,
+// as seen by commands run with the additional environment env,
+// returning them and the offset of the server's clock from this one's.
+// It ends the session.
+func (c *Conn) describe(env []string) (*Details, time.Duration, error) {
+	start := time.Now()
+	if err := c.writePacket(&Request{Type: "Info", Env: env}, nil); err != nil {
+		return nil, 0, err
+	}
+	resp, _, err := c.readResponse()
+	if err != nil {
+		return nil, 0, err
+	}
+	end := time.Now()
+	if resp.Type != "Info" || resp.Details == nil {
+		return nil, 0, fmt.Errorf("unexpected response type %q", resp.Type)
+	}
+	// The server read its clock somewhere in the round trip;
+	// assume the middle.
+	offset := resp.Details.Time.Sub(start.Add(end.Sub(start) / 2))
+	return resp.Details, offset, nil
+}
+
+// cmdInfo implements "mote info [-json] [@name]".
+func cmdInfo(args []string) {
+	flags := flag.NewFlagSet("info", flag.ExitOnError)
+	flags.Usage = usage
+	asJSON := flags.Bool("json", false, "print the information as JSON")
+	flags.Parse(args)
+	server := ""
+	switch flags.NArg() {
+	case 0:
+	case 1:
+		if !strings.HasPrefix(flags.Arg(0), "@") {
+			usage()
+		}
+		server = flags.Arg(0)[1:]
+	default:
+		usage()
+	}
+	list, err := resolveAliases(server, "")
+	if err != nil {
+		log.Fatal(err)
+	}
+	conn, a, url, err := dialAliases(list)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer conn.Close()
+
+	var d *Details
+	var offset time.Duration
+	if conn.has(capInfo) {
+		d, offset, err = conn.describe(a.Env)
+		if err != nil {
+			log.Fatal(conn.abort(err))
+		}
+	} else {
+		log.Printf("server does not report details; showing its Info response only")
+	}
+
+	if *asJSON {
+		js, err := json.MarshalIndent(struct {
+			URL     string
+			GOOS    string
+			GOARCH  string
+			Level   string   `json:",omitzero"`
+			Version int      // protocol version
+			Caps    []string `json:",omitzero"`
+			Load    int
+			*Details
+			ClockOffset time.Duration `json:",omitzero"`
+		}{url, conn.GOOS, conn.GOARCH, conn.Level, conn.Version, conn.Caps, conn.Load, d, offset}, "", "\t")
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("%s\n", js)
+		return
+	}
+
+	type line struct{ key, value string }
+	system := conn.GOOS + "-" + conn.GOARCH
+	if conn.Level != "" {
+		system += " (" + levelVar[conn.GOARCH] + "=" + conn.Level + ")"
+	}
+	lines := []line{
+		{"server", url},
+		{"system", system},
+		{"protocol", fmt.Sprintf("version %d (%s)", conn.Version, strings.Join(conn.Caps, " "))},
+		{"load", fmt.Sprintf("%d commands", conn.Load)},
+	}
+	if d != nil {
+		cpus := fmt.Sprintf("%d CPUs", d.CPUs)
+		if d.CPU != "" {
+			cpus = d.CPU + ", " + cpus
+		}
+		mem := "unknown"
+		if d.Memory > 0 {
+			mem = fmt.Sprintf("%.1f GB", float64(d.Memory)/1e9)
+		}
+		goVersion := d.Go
+		if goVersion == "" {
+			goVersion = "not installed"
+		}
+		cache := fmt.Sprintf("%d files, %.1f MB, unused files kept %v", d.CacheFiles, float64(d.CacheSize)/1e6, d.CacheAge)
+		if d.CacheLimit > 0 {
+			cache += fmt.Sprintf(", limit %.1f MB", float64(d.CacheLimit)/1e6)
+		}
+		lines = append(lines,
+			line{"mote", d.Mote},
+			line{"os", d.OS},
+			line{"cpu", cpus},
+			line{"features", strings.Join(d.Features, " ")},
+			line{"memory", mem},
+			line{"go", goVersion},
+			line{"cache", cache},
+			line{"clock", clockOffset(offset)},
+		)
+	}
+	w := 0
+	for _, l := range lines {
+		w = max(w, len(l.key))
+	}
+	for _, l := range lines {
+		fmt.Printf("%-*s %s\n", w, l.key, l.value)
+	}
+}
+
+// clockOffset describes the offset of a server's clock from this one's.
+func clockOffset(d time.Duration) string {
+	d = d.Round(time.Millisecond)
+	if d >= 0 {
+		return fmt.Sprintf("+%v relative to this machine", d)
+	}
+	return fmt.Sprintf("%v relative to this machine", d)
+}
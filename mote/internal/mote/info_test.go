@@ -0,0 +1,91 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mote
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestInfo(t *testing.T) {
+	setupDirs(t)
+	conn := startServeClient(t, "")
+	if !conn.has(capInfo) {
+		t.Fatalf("server does not have %q capability", capInfo)
+	}
+	d, offset, err := conn.describe(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.CPUs != runtime.NumCPU() {
+		t.Errorf("CPUs = %d, want %d", d.CPUs, runtime.NumCPU())
+	}
+	if d.Mote == "" {
+		t.Errorf("Mote version is empty")
+	}
+	if d.CacheFiles != 0 || d.CacheSize != 0 {
+		t.Errorf("empty cache has %d files, %d bytes", d.CacheFiles, d.CacheSize)
+	}
+	// The server shares this machine's clock.
+	if offset < -time.Second || offset > time.Second {
+		t.Errorf("clock offset = %v, want about 0", offset)
+	}
+}
+
+func TestInfoGoPath(t *testing.T) {
+	// The go reported is the one on the PATH the client's commands
+	// run with, not the server's own.
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a shell script")
+	}
+	setupDirs(t)
+	dir := t.TempDir()
+	script := "#!/bin/sh\necho go version fake\n"
+	if err := os.WriteFile(filepath.Join(dir, "go"), []byte(script), 0o777); err != nil {
+		t.Fatal(err)
+	}
+	path := "PATH=" + dir + string(os.PathListSeparator) + os.Getenv("PATH")
+	d, _, err := startServeClient(t, "").describe([]string{path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Go != "go version fake" {
+		t.Errorf("Go = %q, want %q", d.Go, "go version fake")
+	}
+
+	// And the commands do run that one.
+	var outb bytes.Buffer
+	w, err := startServeClient(t, "").Run(t.Context(), &Exec{
+		Args:   []string{"go", "version"},
+		Dir:    "/mote-test",
+		Env:    []string{path},
+		Stdout: &outb,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != 0 || outb.String() != "go version fake\n" {
+		t.Errorf("go version: code=%d stdout=%q, want 0, %q", w.Code, outb.String(), "go version fake\n")
+	}
+}
+
+func TestClockOffset(t *testing.T) {
+	for _, tt := range []struct {
+		d    time.Duration
+		want string
+	}{
+		{0, "+0s relative to this machine"},
+		{1234567 * time.Microsecond, "+1.235s relative to this machine"},
+		{-20 * time.Millisecond, "-20ms relative to this machine"},
+	} {
+		if got := clockOffset(tt.d); got != tt.want {
+			t.Errorf("clockOffset(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
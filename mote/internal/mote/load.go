@@ -0,0 +1,95 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mote
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+)
+
+// Server load, for choosing among the servers in a group.
+//
+// A server reports its load in the Info response: the number of
+// commands in progress on the machine, from setup to exit, under this
+// server process or any other mote server sharing its cache directory
+// (every ssh:// session is a process of its own). Each command in
+// progress holds a locked file in the busy subdirectory of the cache
+// directory. A file left behind by a server that died is unlocked, and
+// counting removes it. So that counting never sees a file before it is
+// locked, the file is created and locked under a name beginning with a
+// dot, which counting skips, and then renamed into place. On systems
+// without file locks, such files count until the busy directory is
+// deleted.
+
+// busyName is the name of the busy subdirectory of the cache directory.
+const busyName = "busy"
+
+// busySeq numbers the busy files created by this process.
+var busySeq atomic.Int64
+
+// markBusy records a command in progress, returning a function that
+// removes the record. The load is only advice, so failures are ignored.
+func markBusy() (unmark func()) {
+	cache, err := cacheDir()
+	if err != nil {
+		return func() {}
+	}
+	dir := filepath.Join(cache, busyName)
+	os.MkdirAll(dir, 0o777)
+	base := fmt.Sprintf("%d.%d", os.Getpid(), busySeq.Add(1))
+	name := filepath.Join(dir, base)
+	tmp := filepath.Join(dir, "."+base)
+	f, err := lockFile(tmp)
+	if err != nil {
+		return func() {}
+	}
+	if f == nil {
+		// No file locks on this system; just create the file.
+		if err := os.WriteFile(name, nil, 0o666); err != nil {
+			return func() {}
+		}
+	} else if err := os.Rename(tmp, name); err != nil {
+		os.Remove(tmp)
+		f.Close()
+		return func() {}
+	}
+	return func() {
+		os.Remove(name)
+		if f != nil {
+			f.Close()
+		}
+	}
+}
+
+// machineLoad returns the number of commands in progress on the machine.
+func machineLoad() int {
+	cache, err := cacheDir()
+	if err != nil {
+		return 0
+	}
+	dir := filepath.Join(cache, busyName)
+	files, _ := os.ReadDir(dir)
+	n := 0
+	for _, file := range files {
+		if strings.HasPrefix(file.Name(), ".") {
+			continue // not yet locked (see markBusy)
+		}
+		name := filepath.Join(dir, file.Name())
+		f, err := lockFile(name)
+		if errors.Is(err, errLocked) || err == nil && f == nil {
+			n++ // locked by a command in progress, or no locks to tell
+			continue
+		}
+		if f != nil {
+			os.Remove(name)
+			f.Close()
+		}
+	}
+	return n
+}
@@ -0,0 +1,60 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mote
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestMachineLoad(t *testing.T) {
+	setupDirs(t)
+	if n := machineLoad(); n != 0 {
+		t.Fatalf("idle machineLoad() = %d, want 0", n)
+	}
+	unmark1 := markBusy()
+	unmark2 := markBusy()
+	if n := machineLoad(); n != 2 {
+		t.Errorf("machineLoad() = %d, want 2", n)
+	}
+	unmark1()
+	if n := machineLoad(); n != 1 {
+		t.Errorf("machineLoad() after unmark = %d, want 1", n)
+	}
+	unmark2()
+
+	if runtime.GOOS == "windows" {
+		return // no file locks to tell stale files
+	}
+	// A file left by a server that died is not counted, and is removed.
+	dir, err := cacheDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale := filepath.Join(dir, busyName, "1.1")
+	if err := os.WriteFile(stale, nil, 0o666); err != nil {
+		t.Fatal(err)
+	}
+	if n := machineLoad(); n != 0 {
+		t.Errorf("machineLoad() with stale file = %d, want 0", n)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("stale busy file not removed")
+	}
+
+	// A file still being set up, not yet locked, is left alone.
+	tmp := filepath.Join(dir, busyName, ".1.2")
+	if err := os.WriteFile(tmp, nil, 0o666); err != nil {
+		t.Fatal(err)
+	}
+	if n := machineLoad(); n != 0 {
+		t.Errorf("machineLoad() with file being set up = %d, want 0", n)
+	}
+	if _, err := os.Stat(tmp); err != nil {
+		t.Errorf("busy file being set up removed: %v", err)
+	}
+}
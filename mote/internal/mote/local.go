@@ -0,0 +1,35 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mote
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+)
+
+// dialLocal connects to a local:// server, which is a session served
+// in this process, over an in-memory pipe: the whole protocol, upload
+// and temporary tree included, with no network and no other process.
+// It suits scripts that want mote's handling of files on this machine,
+// and tests.
+func dialLocal(u *url.URL) (io.ReadWriteCloser, error) {
+	if u.Host != "" || u.Path != "" {
+		return nil, fmt.Errorf("local server URL must be local://")
+	}
+	return servePipe(nil), nil
+}
+
+// servePipe starts a session served in this process, running programs
+// under emu (see serveEmulated), and returns the client's end of it.
+func servePipe(emu *emulator) io.ReadWriteCloser {
+	cconn, sconn := net.Pipe()
+	go func() {
+		serveEmulated(sconn, "", nil, emu)
+		sconn.Close()
+	}()
+	return cconn
+}
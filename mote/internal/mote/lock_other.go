@@ -4,7 +4,7 @@
 
 //go:build !unix
 
-package main
+package mote
 
 import "os"
 
@@ -16,3 +16,10 @@ import "os"
 func lockFile(name string) (*os.File, error) {
 	return nil, nil
 }
+
+// waitLockFile does nothing on systems without file locks. Sessions
+// served by one process are still ordered by machineLock; separate
+// processes go unordered.
+func waitLockFile(name string, exclusive bool) (*os.File, error) {
+	return nil, nil
+}
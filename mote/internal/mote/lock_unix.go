@@ -4,7 +4,7 @@
 
 //go:build unix
 
-package main
+package mote
 
 import (
 	"os"
@@ -30,3 +30,29 @@ func lockFile(name string) (*os.File, error) {
 	}
 	return f, nil
 }
+
+// waitLockFile creates or opens the named file and waits for a lock on
+// it, exclusive or shared, returning the open file. Closing the file
+// releases the lock. The file is left readable and writable by others,
+// since all it holds is the lock.
+func waitLockFile(name string, exclusive bool) (*os.File, error) {
+	f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE, 0o666)
+	if err != nil {
+		return nil, err
+	}
+	how := syscall.LOCK_SH
+	if exclusive {
+		how = syscall.LOCK_EX
+	}
+	for {
+		err = syscall.Flock(int(f.Fd()), how)
+		if err != syscall.EINTR {
+			break
+		}
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
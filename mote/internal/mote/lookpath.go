@@ -0,0 +1,63 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mote
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// lookPathEnv is exec.LookPath for a command that runs with the
+// environment env: it looks for file in the directories that env's
+// PATH lists, where the command's own children would find it, instead
+// of in this process's PATH, which the client's Env may have changed.
+// Like exec.LookPath, it skips relative directories. If env sets no
+// PATH, lookPathEnv is exec.LookPath.
+func lookPathEnv(file string, env []string) (string, error) {
+	path, ok := envValue(env, "PATH")
+	if !ok {
+		return exec.LookPath(file)
+	}
+	exts := []string{""}
+	if runtime.GOOS == "windows" {
+		pathext, ok := envValue(env, "PATHEXT")
+		if !ok {
+			pathext = ".com;.exe;.bat;.cmd"
+		}
+		if filepath.Ext(file) == "" {
+			exts = nil
+		}
+		for _, ext := range filepath.SplitList(pathext) {
+			exts = append(exts, strings.ToLower(ext))
+		}
+	}
+	for _, dir := range filepath.SplitList(path) {
+		if !filepath.IsAbs(dir) {
+			continue
+		}
+		for _, ext := range exts {
+			name := filepath.Join(dir, file+ext)
+			if info, err := os.Stat(name); err == nil && !info.IsDir() && (runtime.GOOS == "windows" || info.Mode()&0o111 != 0) {
+				return name, nil
+			}
+		}
+	}
+	return "", &exec.Error{Name: file, Err: exec.ErrNotFound}
+}
+
+// envValue returns the value of the last setting of key in env,
+// ignoring case on Windows, as the system does.
+func envValue(env []string, key string) (string, bool) {
+	for i := len(env) - 1; i >= 0; i-- {
+		k, v, ok := strings.Cut(env[i], "=")
+		if ok && (k == key || runtime.GOOS == "windows" && strings.EqualFold(k, key)) {
+			return v, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,49 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mote
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestLookPathEnv(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("checks Unix execute bits")
+	}
+	dir1, dir2 := t.TempDir(), t.TempDir()
+	for _, f := range []struct {
+		name string
+		mode os.FileMode
+	}{
+		{filepath.Join(dir1, "tool"), 0o666}, // not executable
+		{filepath.Join(dir2, "tool"), 0o777},
+		{filepath.Join(dir1, "other"), 0o777},
+	} {
+		if err := os.WriteFile(f.name, nil, f.mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+	env := []string{"PATH=/nonexistent", "PATH=rel:" + dir1 + ":" + dir2}
+	for _, tt := range []struct{ file, want string }{
+		{"tool", filepath.Join(dir2, "tool")},
+		{"other", filepath.Join(dir1, "other")},
+		{"missing", ""},
+	} {
+		got, err := lookPathEnv(tt.file, env)
+		if got != tt.want || (err != nil) != (tt.want == "") {
+			t.Errorf("lookPathEnv(%q) = %q, %v, want %q", tt.file, got, err, tt.want)
+		}
+	}
+
+	// Without PATH in env, it is exec.LookPath.
+	want, wantErr := exec.LookPath("sh")
+	if got, err := lookPathEnv("sh", []string{"HOME=/"}); got != want || (err != nil) != (wantErr != nil) {
+		t.Errorf("lookPathEnv(sh) without PATH = %q, %v, want %q, %v", got, err, want, wantErr)
+	}
+}
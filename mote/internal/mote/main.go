@@ -0,0 +1,127 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mote
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"runtime/debug"
+	"time"
+)
+
+var usageMessage = `Usage: mote [-u path]... [@name] cmd [args...]
+	mote alias [name [URL]]
+	mote clean [-n] [-older duration]
+	mote close [URL]
+	mote discover [-y]
+	mote go-setup [-list | -remove]
+	mote info [-json] [@name]
+	mote login URL
+	mote serve URL
+	mote trace [-conn pid.n] file
+	mote trace -replay [-conn pid.n] [@name] file
+	mote version
+`
+
+func usage() {
+	fmt.Fprint(os.Stderr, usageMessage)
+	moteFlags.PrintDefaults()
+	os.Exit(2)
+}
+
+// moteFlags holds the mote command's flags. It is not flag.CommandLine
+// so that programs importing rsc.io/cmd/mote/client, which is built on
+// this package, do not find mote's flags among their own.
+var moteFlags = flag.NewFlagSet("mote", flag.ExitOnError)
+
+var (
+	uploads     uploadFlag
+	testData    = moteFlags.Bool("t", false, "upload testdata directories up to module root")
+	verbose     = moteFlags.Bool("v", false, "print verbose output")
+	exclusive   = moteFlags.Bool("exclusive", false, "wait for exclusive use of the server (for benchmarking)")
+	link        = moteFlags.String("link", "", "place uploaded files on the server by `mode` copy, clone, or hard")
+	stats       = moteFlags.Bool("stats", false, "print the remote command's resource usage")
+	watch       = moteFlags.Bool("watch", false, "rerun the command whenever its binary or uploaded files change")
+	coreDir     = moteFlags.String("core", "", "if the remote command crashes, save its core dump and binary in `dir`")
+	metricsAddr = moteFlags.String("metrics", "", "with serve, serve Prometheus metrics at http://`addr`/metrics")
+	drainTime   = moteFlags.Duration("drain", time.Minute, "with serve, how long running commands may finish when the server is stopped")
+)
+
+type uploadFlag []string
+
+func (f *uploadFlag) String() string { return fmt.Sprint([]string(*f)) }
+
+func (f *uploadFlag) Set(s string) error {
+	*f = append(*f, s)
+	return nil
+}
+
+// isMote reports whether this process is the mote command,
+// as opposed to a program using the client package.
+var isMote bool
+
+// Main runs the mote command.
+func Main() {
+	isMote = true
+	log.SetPrefix("mote: ")
+	log.SetFlags(0)
+
+	moteFlags.Var(&uploads, "u", "upload `path` into remote directory tree (may be repeated)")
+	moteFlags.Var(forwardFlag{remote: false}, "L", "forward connections to the client's port to host:hostport from the server, given as `port:host:hostport` (may be repeated)")
+	moteFlags.Var(forwardFlag{remote: true}, "R", "forward connections to the server's port to host:hostport from the client, given as `port:host:hostport` (may be repeated)")
+	moteFlags.Usage = usage
+	moteFlags.Parse(os.Args[1:])
+	args := moteFlags.Args()
+	if len(args) == 0 {
+		usage()
+	}
+
+	switch args[0] {
+	case "alias":
+		cmdAlias(args[1:])
+	case "clean":
+		cmdClean(args[1:])
+	case "close":
+		cmdClose(args[1:])
+	case "discover":
+		cmdDiscover(args[1:])
+	case "serve":
+		cmdServe(args[1:])
+	case "info":
+		cmdInfo(args[1:])
+	case "login":
+		cmdLogin(args[1:])
+	case "go-setup":
+		cmdGoSetup(args[1:])
+	case "trace":
+		cmdTrace(args[1:])
+	case "version":
+		cmdVersion(args[1:])
+	case "tail-daemon":
+		// Not in usageMessage: mote runs this for itself,
+		// in the background. See taildaemon.go.
+		cmdTailDaemon(args[1:])
+	default:
+		cmdRun(args)
+	}
+}
+
+func cmdVersion(args []string) {
+	if len(args) != 0 {
+		usage()
+	}
+	fmt.Printf("mote %s\n", moteVersion())
+}
+
+// moteVersion returns the module version of this mote,
+// or "(unknown)" if it was not built from a versioned module.
+func moteVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	return "(unknown)"
+}
@@ -0,0 +1,150 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mote
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Server metrics, for operators watching a fleet of servers.
+//
+// "mote -metrics addr serve URL" serves the counters below over HTTP
+// at addr, in the Prometheus text exposition format, at /metrics.
+// The counters cover every session this process serves; for tail://,
+// where the Tailscale daemon runs the sessions, the daemon serves them.
+
+// serverMetrics holds the counters for the sessions served by this process.
+var serverMetrics metrics
+
+// A metrics is a set of server counters.
+type metrics struct {
+	active       atomic.Int64 // sessions in progress
+	sessions     atomic.Int64 // sessions started
+	setupFails   atomic.Int64 // sessions that failed before the command ran
+	uploadBytes  atomic.Int64 // file content received in Upload requests
+	cacheHits    atomic.Int64 // files found by inCache
+	cacheMisses  atomic.Int64 // files not found by inCache
+	commands     atomic.Int64 // commands that ran to completion
+	commandFails atomic.Int64 // commands that exited unsuccessfully
+	commandTime  atomic.Int64 // total running time of commands, in nanoseconds
+
+	mu             sync.Mutex
+	handshakeFails map[string]int64 // failed handshakes, by reason
+	lastCacheScan  time.Time        // time of the cached cache usage values
+	cacheBytes     int64            // disk usage of the cache at lastCacheScan
+	cacheFiles     int64            // files in the cache at lastCacheScan
+	scanning       bool             // a scrape is scanning the cache
+}
+
+// handshakeFailed records a failed handshake, classifying err by reason.
+func (m *metrics) handshakeFailed(err error) {
+	reason := "protocol"
+	switch {
+	case errors.Is(err, os.ErrDeadlineExceeded):
+		reason = "timeout"
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, net.ErrClosed):
+		reason = "hangup"
+	case strings.Contains(err.Error(), "incorrect password"):
+		reason = "password"
+	case strings.Contains(err.Error(), "not binary safe"):
+		reason = "binary"
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.handshakeFails == nil {
+		m.handshakeFails = make(map[string]int64)
+	}
+	m.handshakeFails[reason]++
+}
+
+// cacheScanInterval is how often a scrape rescans the cache directory.
+// Walking a large cache on every scrape would cost more than it tells.
+const cacheScanInterval = time.Minute
+
+// cacheUsage returns the disk usage of the cache and the number of
+// files in it, rescanning the cache if the last scan is old.
+func (m *metrics) cacheUsage() (bytes, files int64) {
+	m.mu.Lock()
+	if time.Since(m.lastCacheScan) < cacheScanInterval || m.scanning {
+		defer m.mu.Unlock()
+		return m.cacheBytes, m.cacheFiles
+	}
+	m.scanning = true
+	m.mu.Unlock()
+
+	entries, _ := cacheEntries() // an unusable cache is an empty one
+	for _, e := range entries {
+		bytes += e.size
+		files++
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.scanning = false
+	m.lastCacheScan = time.Now()
+	m.cacheBytes, m.cacheFiles = bytes, files
+	return bytes, files
+}
+
+// WriteTo writes the metrics to w in the Prometheus text format.
+func (m *metrics) WriteTo(w io.Writer) (int64, error) {
+	cacheBytes, cacheFiles := m.cacheUsage()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var b strings.Builder
+	metric := func(name, typ, help string, value any) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n%s %v\n", name, help, name, typ, name, value)
+	}
+	metric("mote_sessions_active", "gauge", "Sessions in progress.", m.active.Load())
+	metric("mote_sessions_total", "counter", "Sessions started.", m.sessions.Load())
+	fmt.Fprintf(&b, "# HELP mote_handshake_failures_total Failed connection handshakes, by reason.\n")
+	fmt.Fprintf(&b, "# TYPE mote_handshake_failures_total counter\n")
+	for _, reason := range slices.Sorted(maps.Keys(m.handshakeFails)) {
+		fmt.Fprintf(&b, "mote_handshake_failures_total{reason=%q} %d\n", reason, m.handshakeFails[reason])
+	}
+	metric("mote_setup_failures_total", "counter", "Sessions that failed before their command ran.", m.setupFails.Load())
+	metric("mote_upload_bytes_total", "counter", "Bytes of file content uploaded.", m.uploadBytes.Load())
+	metric("mote_cache_hits_total", "counter", "Files found in the cache.", m.cacheHits.Load())
+	metric("mote_cache_misses_total", "counter", "Files missing from the cache.", m.cacheMisses.Load())
+	metric("mote_cache_bytes", "gauge", "Disk space used by the cache.", cacheBytes)
+	metric("mote_cache_files", "gauge", "Files in the cache.", cacheFiles)
+	metric("mote_command_failures_total", "counter", "Commands that exited unsuccessfully.", m.commandFails.Load())
+	fmt.Fprintf(&b, "# HELP mote_command_duration_seconds Running time of commands.\n")
+	fmt.Fprintf(&b, "# TYPE mote_command_duration_seconds summary\n")
+	fmt.Fprintf(&b, "mote_command_duration_seconds_sum %g\n", time.Duration(m.commandTime.Load()).Seconds())
+	fmt.Fprintf(&b, "mote_command_duration_seconds_count %d\n", m.commands.Load())
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// serveMetrics starts serving the metrics over HTTP at addr, at /metrics,
+// in the background, until the returned listener is closed.
+func serveMetrics(addr string) (net.Listener, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("metrics: %v", err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		serverMetrics.WriteTo(w)
+	})
+	log.Printf("serving metrics at http://%s/metrics", ln.Addr())
+	go http.Serve(ln, mux)
+	return ln, nil
+}
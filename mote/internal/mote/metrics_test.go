@@ -0,0 +1,101 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mote
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetrics(t *testing.T) {
+	setupDirs(t)
+	m := &serverMetrics
+	m.mu.Lock()
+	m.lastCacheScan = time.Time{} // rescan this test's cache
+	m.mu.Unlock()
+	sessions, uploaded := m.sessions.Load(), m.uploadBytes.Load()
+	hits, misses := m.cacheHits.Load(), m.cacheMisses.Load()
+	commands, commandFails := m.commands.Load(), m.commandFails.Load()
+
+	// Upload a script and run it twice: one miss, then one hit.
+	dir := t.TempDir()
+	script := filepath.Join(dir, "x.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\nexit 1\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	var files []*File
+	if err := addFile(&files, script); err != nil {
+		t.Fatal(err)
+	}
+	for range 2 {
+		runPipe(t, "", files, filepath.ToSlash(dir), []string{"./x.sh"})
+	}
+
+	// A client with the wrong password fails the handshake.
+	cconn, sconn := net.Pipe()
+	go func() {
+		serve(sconn, "right", nil)
+		sconn.Close()
+	}()
+	if _, err := clientConn(cconn, "wrong"); err == nil {
+		t.Fatalf("clientConn with wrong password succeeded")
+	}
+	cconn.Close()
+
+	// Wait for the failed session to be counted.
+	deadline := time.Now().Add(10 * time.Second)
+	for m.active.Load() != 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	check := func(name string, got, want int64) {
+		t.Helper()
+		if got != want {
+			t.Errorf("%s = %d, want %d", name, got, want)
+		}
+	}
+	check("sessions", m.sessions.Load()-sessions, 3)
+	check("uploadBytes", m.uploadBytes.Load()-uploaded, files[0].Size)
+	check("cacheHits", m.cacheHits.Load()-hits, 1)
+	check("cacheMisses", m.cacheMisses.Load()-misses, 1)
+	check("commands", m.commands.Load()-commands, 2)
+	check("commandFails", m.commandFails.Load()-commandFails, 2)
+
+	// The same counters, as served over HTTP.
+	ln, err := serveMetrics("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	resp, err := http.Get(fmt.Sprintf("http://%s/metrics", ln.Addr()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := string(data)
+	for _, want := range []string{
+		"# TYPE mote_sessions_active gauge\nmote_sessions_active 0\n",
+		fmt.Sprintf("\nmote_sessions_total %d\n", m.sessions.Load()),
+		"\nmote_handshake_failures_total{reason=\"password\"} ",
+		fmt.Sprintf("\nmote_cache_bytes %d\n", files[0].Size),
+		"\nmote_cache_files 1\n",
+		fmt.Sprintf("\nmote_command_duration_seconds_count %d\n", m.commands.Load()),
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("metrics missing %q:\n%s", want, text)
+		}
+	}
+}
@@ -2,15 +2,17 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-package main
+package mote
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"log"
 	"net"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"runtime"
 	"strings"
@@ -18,17 +20,28 @@ import (
 	"time"
 )
 
-// TestMain lets the test binary stand in for ssh, gomote, and go
+// TestMain lets the test binary stand in for ssh, gomote, go, mote, and qemu
 // when invoked under those names, so that the subprocess transports
 // can be tested without the real commands. See doc.go's TESTING comment.
+// It also stands in for fwdcheck, a command for the forwarding tests,
+// and crash, a command for the core dump tests.
 func TestMain(m *testing.M) {
 	switch filepath.Base(os.Args[0]) {
+	case "mote":
+		Main()
+		os.Exit(0)
+	case "qemu-riscv64":
+		qemuMockMain()
 	case "ssh":
 		sshMockMain()
 	case "gomote":
 		gomoteMockMain()
 	case "go":
 		goMockMain()
+	case "fwdcheck":
+		fwdCheckMain()
+	case "crash":
+		panic("crash")
 	}
 	os.Exit(m.Run())
 }
@@ -37,6 +50,8 @@ func TestMain(m *testing.M) {
 func setupDirs(t *testing.T) {
 	t.Setenv("MOTECONFIG", t.TempDir())
 	t.Setenv("MOTECACHE", t.TempDir())
+	// The ssh mock's remote home, where an install lands.
+	t.Setenv("MOTE_TEST_SSH_HOME", t.TempDir())
 }
 
 // runPipe runs a full client/server session over an in-memory pipe.
@@ -54,7 +69,7 @@ func runPipe(t *testing.T, password string, files []*File, dir string, args []st
 		t.Fatalf("clientConn: %v", err)
 	}
 	var outb, errb bytes.Buffer
-	w, err := conn.Run(&Exec{Args: args, Dir: dir, Files: files, Stdout: &outb, Stderr: &errb})
+	w, err := conn.Run(t.Context(), &Exec{Args: args, Dir: dir, Files: files, Stdout: &outb, Stderr: &errb})
 	if err != nil {
 		t.Fatalf("Run: %v", err)
 	}
@@ -159,7 +174,14 @@ func TestRelativeUpload(t *testing.T) {
 
 func TestCleanCache(t *testing.T) {
 	setupDirs(t)
-	stale, fresh := cacheFile(strings.Repeat("aa", 32)), cacheFile(strings.Repeat("bb", 32))
+	stale, err := cacheFile(strings.Repeat("aa", 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fresh, err := cacheFile(strings.Repeat("bb", 32))
+	if err != nil {
+		t.Fatal(err)
+	}
 	for _, file := range []string{stale, fresh} {
 		if err := os.MkdirAll(filepath.Dir(file), 0o777); err != nil {
 			t.Fatal(err)
@@ -226,6 +248,214 @@ func TestKill(t *testing.T) {
 	}
 }
 
+func TestStdin(t *testing.T) {
+	setupDirs(t)
+	// More input than fits in one Stdin request, read by the command
+	// only after a pause, so that it has to wait in the server's queue.
+	in := strings.Repeat("hello, world\n", 10000)
+	var outb, errb bytes.Buffer
+	w, err := startServeClient(t, "").Run(t.Context(), &Exec{
+		Args:   []string{"sh", "-c", "sleep 0.2; cat; echo done"},
+		Dir:    "/mote-test",
+		Stdin:  strings.NewReader(in),
+		Stdout: &outb,
+		Stderr: &errb,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != 0 || outb.String() != in+"done\n" {
+		t.Errorf("code=%d stdout=%d bytes, stderr=%q; want 0, %d bytes", w.Code, outb.Len(), errb.String(), len(in)+len("done\n"))
+	}
+}
+
+func TestUsage(t *testing.T) {
+	setupDirs(t)
+	var outb, errb bytes.Buffer
+	w, err := startServeClient(t, "").Run(t.Context(), &Exec{
+		Args:   []string{"sh", "-c", "echo hello; echo error >&2"},
+		Dir:    "/mote-test",
+		Stdout: &outb,
+		Stderr: &errb,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := w.Usage
+	if u == nil {
+		t.Fatal("Run: no Usage")
+	}
+	if u.Output != int64(len("hello\nerror\n")) {
+		t.Errorf("Usage.Output = %d, want %d", u.Output, len("hello\nerror\n"))
+	}
+	if u.Wall <= 0 {
+		t.Errorf("Usage.Wall = %v, want > 0", u.Wall)
+	}
+	if runtime.GOOS != "windows" && u.MaxRSS <= 0 {
+		t.Errorf("Usage.MaxRSS = %d, want > 0", u.MaxRSS)
+	}
+}
+
+func TestRunContext(t *testing.T) {
+	setupDirs(t)
+
+	// Canceling the context kills a running command,
+	// even one not reading the input it is being sent.
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	ctx, cancel := context.WithCancel(t.Context())
+	started := make(chan bool, 1)
+	type result struct {
+		w   *Wait
+		err error
+	}
+	done := make(chan result, 1)
+	conn := startServeClient(t, "")
+	go func() {
+		w, err := conn.Run(ctx, &Exec{
+			Args:  []string{"sh", "-c", "echo started; sleep 300"},
+			Dir:   "/mote-test",
+			Stdin: pr,
+			Stdout: writerFunc(func(b []byte) (int, error) {
+				select {
+				case started <- true:
+				default:
+				}
+				return len(b), nil
+			}),
+		})
+		done <- result{w, err}
+	}()
+	<-started
+	cancel()
+	select {
+	case r := <-done:
+		if r.err != nil || r.w.Code >= 0 {
+			t.Errorf("Run = %+v, %v; want signal death", r.w, r.err)
+		}
+	case <-time.After(30 * time.Second):
+		t.Fatalf("Run did not return after cancel")
+	}
+
+	// A context done before the command starts stops Run.
+	ctx, cancel = context.WithCancel(t.Context())
+	cancel()
+	if _, err := startServeClient(t, "").Run(ctx, &Exec{Args: []string{"echo", "hi"}, Dir: "/mote-test"}); err != context.Canceled {
+		t.Errorf("Run with canceled context = %v, want %v", err, context.Canceled)
+	}
+}
+
+// A writerFunc is an io.Writer implemented by a function.
+type writerFunc func([]byte) (int, error)
+
+func (f writerFunc) Write(b []byte) (int, error) { return f(b) }
+
+// startSleeper starts a command on conn that prints "started" and then
+// waits for release to be called, creating the file dir/ended as it
+// exits. startSleeper returns once the command is running, which means
+// it holds its reservation.
+func startSleeper(t *testing.T, conn *Conn, exclusive bool, dir string) (release func()) {
+	t.Helper()
+	script := `echo started; while [ ! -e "$1/release" ]; do sleep 0.01; done; touch "$1/ended"`
+	req := &Request{Type: "Setup", Args: []string{"sh", "-c", script, "sh", dir}, Dir: "/mote-test", Exclusive: exclusive}
+	if err := conn.writePacket(req, nil); err != nil {
+		t.Fatal(err)
+	}
+	var resp Response
+	if _, err := conn.readPacket(&resp); err != nil || resp.Type != "Ready" {
+		t.Fatalf("got %+v, %v; want Ready", resp, err)
+	}
+	if err := conn.writePacket(&Request{Type: "Start"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	for resp.Type != "Output" {
+		if _, err := conn.readPacket(&resp); err != nil || resp.Type != "Output" && resp.Type != "Exclusive" {
+			t.Fatalf("got %+v, %v; want Output", resp, err)
+		}
+	}
+	// Read the rest of the session, so that it can end.
+	go func() {
+		for {
+			if _, err := conn.readPacket(&resp); err != nil {
+				return
+			}
+		}
+	}()
+	release = func() { os.WriteFile(filepath.Join(dir, "release"), nil, 0o666) }
+	t.Cleanup(release)
+	return release
+}
+
+// waitLoad waits until the machine has n commands in progress.
+func waitLoad(n int) {
+	for machineLoad() != n {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestExclusive(t *testing.T) {
+	setupDirs(t)
+
+	// An exclusive command waits for the running command to finish:
+	// it runs only once the sleeper has ended, and the server reports
+	// the wait.
+	dir := t.TempDir()
+	release := startSleeper(t, startServeClient(t, ""), false, dir)
+	type result struct {
+		w   *Wait
+		out string
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		var outb bytes.Buffer
+		w, err := startServeClient(t, "").Run(t.Context(), &Exec{
+			Args:      []string{"sh", "-c", `test -e "$1/ended" && echo alone`, "sh", dir},
+			Dir:       "/mote-test",
+			Exclusive: true,
+			Stdout:    &outb,
+			Stderr:    &outb,
+		})
+		done <- result{w, outb.String(), err}
+	}()
+	waitLoad(2) // the exclusive command is set up, waiting
+	release()
+	r := <-done
+	if r.err != nil {
+		t.Fatal(r.err)
+	}
+	if r.w.Code != 0 || r.out != "alone\n" {
+		t.Errorf("exclusive: code=%d output=%q, want 0, %q", r.w.Code, r.out, "alone\n")
+	}
+	if r.w.Waited <= 0 {
+		t.Errorf("exclusive command waited %v, want > 0", r.w.Waited)
+	}
+
+	// An ordinary command waits for a running exclusive one.
+	waitLoad(0)
+	dir = t.TempDir()
+	release = startSleeper(t, startServeClient(t, ""), true, dir)
+	go func() {
+		var outb bytes.Buffer
+		w, err := startServeClient(t, "").Run(t.Context(), &Exec{
+			Args:   []string{"sh", "-c", `test -e "$1/ended" && echo after`, "sh", dir},
+			Dir:    "/mote-test",
+			Stdout: &outb,
+			Stderr: &outb,
+		})
+		done <- result{w, outb.String(), err}
+	}()
+	waitLoad(2)
+	release()
+	r = <-done
+	if r.err != nil {
+		t.Fatal(r.err)
+	}
+	if r.w.Code != 0 || r.out != "after\n" {
+		t.Errorf("ordinary: code=%d output=%q, want 0, %q", r.w.Code, r.out, "after\n")
+	}
+}
+
 func TestBadUploadHash(t *testing.T) {
 	setupDirs(t)
 	conn := startServeClient(t, "")
@@ -302,7 +532,7 @@ func TestVersionAndAliasDispatch(t *testing.T) {
 func runConn(t *testing.T, conn *Conn, args []string, want string) {
 	t.Helper()
 	var outb, errb bytes.Buffer
-	w, err := conn.Run(&Exec{Args: args, Dir: "/mote-test", Stdout: &outb, Stderr: &errb})
+	w, err := conn.Run(t.Context(), &Exec{Args: args, Dir: "/mote-test", Stdout: &outb, Stderr: &errb})
 	if err != nil {
 		t.Fatalf("Run: %v", err)
 	}
@@ -349,7 +579,7 @@ func sshMockMain() {
 		fmt.Fprintf(os.Stderr, "Exit request sent.\n")
 		os.Exit(0)
 	}
-	for _, want := range []string{"ControlMaster auto", "ControlPersist 1800", "ControlPath ~/.ssh/sockets/mote-%r@%h-%p", "kremvax mote serve -"} {
+	for _, want := range []string{"ControlMaster auto", "ControlPersist 1800", "ControlPath ~/.ssh/sockets/mote-%r@%h-%p", "kremvax "} {
 		if !strings.Contains(args, want) {
 			log.Fatalf("missing %q in args: %s", want, args)
 		}
@@ -360,6 +590,61 @@ func sshMockMain() {
 		fmt.Fprintf(os.Stderr, "%s\n", msg)
 		os.Exit(255)
 	}
+	// The remote command, run in the directory $MOTE_TEST_SSH_HOME,
+	// is either the server or an upgrade installing a new one there.
+	home := os.Getenv("MOTE_TEST_SSH_HOME")
+	if home == "" {
+		// Never let an install land in the package directory.
+		home = os.DevNull
+	}
+	switch cmd := os.Args[len(os.Args)-1]; {
+	default:
+		log.Fatalf("unexpected remote command %q", cmd)
+	case cmd == "mote serve -hex-":
+		if os.Getenv("MOTE_TEST_SSH_NOMOTE") != "" {
+			fmt.Fprintf(os.Stderr, "sh: 1: mote: not found\n")
+			os.Exit(127)
+		}
+		if err := serve(serveHex(), "", nil); err != nil {
+			log.Fatal(err)
+		}
+		os.Exit(0)
+	case cmd == "uname -sm":
+		fmt.Printf("Linux x86_64\n")
+		os.Exit(0)
+	case cmd == "mote serve -":
+		if os.Getenv("MOTE_TEST_SSH_NOMOTE") != "" {
+			fmt.Fprintf(os.Stderr, "sh: 1: mote: not found\n")
+			os.Exit(127)
+		}
+		if os.Getenv("MOTE_TEST_SSH_OLD") != "" {
+			serverVersion, serverCaps = 0, nil
+		}
+	case cmd == sshInstallPath+" serve -":
+		if _, err := os.Stat(filepath.Join(home, sshInstallPath)); err != nil {
+			fmt.Fprintf(os.Stderr, "sh: 1: %s: not found\n", sshInstallPath)
+			os.Exit(127)
+		}
+	case strings.HasPrefix(cmd, "mkdir -p .cache/mote/bin && cat >"):
+		// Run the install script as the server's shell would,
+		// losing the binary's last byte if asked.
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if os.Getenv("MOTE_TEST_SSH_TRUNCATE") != "" {
+			data = data[:len(data)-1]
+		}
+		c := exec.Command("sh", "-c", cmd)
+		c.Dir = home
+		c.Stdin = bytes.NewReader(data)
+		c.Stdout = os.Stdout
+		c.Stderr = os.Stderr
+		if err := c.Run(); err != nil {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
 	if msg := os.Getenv("MOTE_TEST_SSH_DIE"); msg != "" {
 		// Simulate the connection dying mid-session: the handshake and
 		// Info exchange succeed, and then the connection is gone.
@@ -390,6 +675,167 @@ func TestSSHTransport(t *testing.T) {
 	runConn(t, conn, []string{"echo", "over ssh"}, "over ssh\n")
 }
 
+func TestSSHUpgrade(t *testing.T) {
+	setupDirs(t)
+	mockPATH(t, "ssh", "go")
+	home := t.TempDir()
+	t.Setenv("MOTE_TEST_SSH_HOME", home)
+	t.Setenv("MOTE_TEST_SSH_OLD", "1")
+
+	// Without $MOTEUPGRADE, the old server is used as is.
+	conn, err := dialServer("ssh://kremvax")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conn.Version != 0 || conn.Caps != nil {
+		t.Errorf("old server reported version %d, caps %v", conn.Version, conn.Caps)
+	}
+	conn.Close()
+
+	// With it, a current mote is built, installed, and used.
+	t.Setenv("MOTEUPGRADE", "1")
+	conn, err = dialServer("ssh://kremvax")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if !conn.current() {
+		t.Errorf("upgraded server reported version %d, caps %v", conn.Version, conn.Caps)
+	}
+	data, err := os.ReadFile(filepath.Join(home, sshInstallPath))
+	if err != nil || string(data) != "dummy" {
+		t.Errorf("installed mote = %q, %v, want dummy binary", data, err)
+	}
+	runConn(t, conn, []string{"echo", "upgraded"}, "upgraded\n")
+}
+
+func TestSSHInstall(t *testing.T) {
+	// A server with no mote gets one installed, if asked.
+	setupDirs(t)
+	mockPATH(t, "ssh", "go")
+	home := t.TempDir()
+	t.Setenv("MOTE_TEST_SSH_HOME", home)
+	t.Setenv("MOTE_TEST_SSH_NOMOTE", "1")
+
+	// Without $MOTEUPGRADE, nothing is installed, and the failure
+	// is reported as it is.
+	_, err := dialServer("ssh://kremvax")
+	if err == nil || !strings.Contains(err.Error(), "mote: not found") {
+		t.Errorf("dial without $MOTEUPGRADE = %v, want not found", err)
+	}
+	file := filepath.Join(home, sshInstallPath)
+	if _, err := os.Stat(file); err == nil {
+		t.Fatalf("mote installed without $MOTEUPGRADE")
+	}
+
+	t.Setenv("MOTEUPGRADE", "1")
+
+	// Nor is anything installed when ssh fails for another reason.
+	t.Setenv("MOTE_TEST_SSH_FAIL", "Permission denied (publickey).")
+	_, err = dialServer("ssh://kremvax")
+	if err == nil || !strings.Contains(err.Error(), "Permission denied") {
+		t.Errorf("dial with failing ssh = %v, want Permission denied", err)
+	}
+	if _, err := os.Stat(file); err == nil {
+		t.Fatalf("mote installed after ssh failure")
+	}
+	t.Setenv("MOTE_TEST_SSH_FAIL", "")
+
+	// Nor over ssh+hex://, which cannot carry the binary intact.
+	_, err = dialServer("ssh+hex://kremvax")
+	if err == nil || !strings.Contains(err.Error(), "not binary safe") {
+		t.Errorf("dial ssh+hex:// = %v, want not binary safe", err)
+	}
+	if _, err := os.Stat(file); err == nil {
+		t.Fatalf("mote installed over ssh+hex://")
+	}
+
+	// A copy damaged on the way is not put in place.
+	t.Setenv("MOTE_TEST_SSH_TRUNCATE", "1")
+	_, err = dialServer("ssh://kremvax")
+	if err == nil || !strings.Contains(err.Error(), "damaged in transit") {
+		t.Errorf("dial with damaged copy = %v, want damaged in transit", err)
+	}
+	if _, err := os.Stat(file); err == nil {
+		t.Fatalf("damaged mote installed")
+	}
+	if _, err := os.Stat(file + ".tmp"); err == nil {
+		t.Errorf("damaged copy left behind")
+	}
+	t.Setenv("MOTE_TEST_SSH_TRUNCATE", "")
+
+	conn, err := dialServer("ssh://kremvax")
+	if err != nil {
+		t.Fatal(err)
+	}
+	runConn(t, conn, []string{"echo", "installed"}, "installed\n")
+	conn.Close()
+	data, err := os.ReadFile(file)
+	if err != nil || string(data) != "dummy" {
+		t.Fatalf("installed mote = %q, %v, want dummy binary", data, err)
+	}
+
+	// Later runs reuse the installed mote.
+	if err := os.WriteFile(file, []byte("kept"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	conn, err = dialServer("ssh://kremvax")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+	if data, _ := os.ReadFile(file); string(data) != "kept" {
+		t.Errorf("installed mote replaced on second run")
+	}
+}
+
+func TestOldServer(t *testing.T) {
+	// Features an older server lacks are dropped with a warning.
+	setupDirs(t)
+	defer func(v int, caps []string) { serverVersion, serverCaps = v, caps }(serverVersion, serverCaps)
+	serverVersion, serverCaps = 0, nil
+
+	cconn, sconn := net.Pipe()
+	defer cconn.Close()
+	go func() {
+		serve(sconn, "", nil)
+		sconn.Close()
+	}()
+	conn, err := clientConn(cconn, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conn.current() {
+		t.Errorf("old server reported as current")
+	}
+	var outb, errb bytes.Buffer
+	w, err := conn.Run(t.Context(), &Exec{
+		Args:      []string{"echo", "hello"},
+		Dir:       "/mote-test",
+		Stdin:     strings.NewReader("input"),
+		Exclusive: true,
+		Link:      linkHard,
+		Forwards:  []*Forward{{Remote: true, Listen: "1", Dial: "localhost:1"}},
+		CoreDir:   t.TempDir(),
+		Stdout:    &outb,
+		Stderr:    &errb,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != 0 || outb.String() != "hello\n" {
+		t.Errorf("Run: code=%d stdout=%q, want 0, %q", w.Code, outb.String(), "hello\n")
+	}
+	if w.Usage != nil {
+		t.Errorf("Run: Usage = %v, want nil from old server", w.Usage)
+	}
+	for _, want := range []string{"standard input", "exclusive use", "-link", "port forwarding", "core dumps"} {
+		if !strings.Contains(errb.String(), want) {
+			t.Errorf("stderr = %q, want warning about %s", errb.String(), want)
+		}
+	}
+}
+
 func TestSSHTransportError(t *testing.T) {
 	// A failed handshake must report the transport's standard error text,
 	// which usually explains what went wrong.
@@ -412,7 +858,7 @@ func TestSSHTransportSessionError(t *testing.T) {
 	if err != nil {
 		t.Fatalf("dialServer: %v", err)
 	}
-	_, err = conn.Run(&Exec{Args: []string{"echo", "hi"}, Dir: "/mote-test", Stdout: io.Discard, Stderr: io.Discard})
+	_, err = conn.Run(t.Context(), &Exec{Args: []string{"echo", "hi"}, Dir: "/mote-test", Stdout: io.Discard, Stderr: io.Discard})
 	if err == nil {
 		t.Fatal("Run succeeded, want error")
 	}
@@ -422,6 +868,52 @@ func TestSSHTransportSessionError(t *testing.T) {
 	}
 }
 
+func TestExecTransport(t *testing.T) {
+	setupDirs(t)
+	mockPATH(t, "mote")
+	for _, url := range []string{
+		"exec://mote serve -",
+		"exec://sh -c 'exec mote serve -'",
+		"exec+hex://mote serve -hex-",
+	} {
+		conn, err := dialServer(url)
+		if err != nil {
+			t.Fatalf("%s: %v", url, err)
+		}
+		runConn(t, conn, []string{"echo", "over exec"}, "over exec\n")
+		conn.Close()
+	}
+}
+
+func TestExecTransportError(t *testing.T) {
+	// A failed handshake reports the command's standard error.
+	setupDirs(t)
+	_, err := dialServer("exec://sh -c 'echo no such pod >&2; exit 1'")
+	if err == nil || !strings.Contains(err.Error(), "no such pod") {
+		t.Fatalf("dialServer: %v, want command stderr in error", err)
+	}
+	if _, err := dialServer("exec://"); err == nil || !strings.Contains(err.Error(), "no command") {
+		t.Fatalf("dialServer(exec://): %v, want no command error", err)
+	}
+}
+
+func TestExecAlias(t *testing.T) {
+	setupDirs(t)
+	const url = `exec://kubectl exec -i "my pod" -- mote serve -`
+	if err := setAlias("pod", url); err != nil {
+		t.Fatal(err)
+	}
+	if err := setAlias("kremvax", "ssh://kremvax"); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := lookupAlias("pod"); err != nil || got != url {
+		t.Fatalf("lookupAlias(pod) = %q, %v, want %q", got, err, url)
+	}
+	if got, err := lookupAlias("kremvax"); err != nil || got != "ssh://kremvax" {
+		t.Fatalf("lookupAlias(kremvax) = %q, %v", got, err)
+	}
+}
+
 // The gomote mock takes direction from the test environment:
 // $MOTE_TEST_GOMOTE_INST is the instance name to expect (and print
 // from create), $MOTE_TEST_GOMOTE_LIST is the "gomote list" output,
@@ -697,14 +1189,14 @@ func TestResolveServer(t *testing.T) {
 	if err := setAlias("kremvax", "ssh://kremvax"); err != nil {
 		t.Fatal(err)
 	}
-	if url, err := resolveServer("kremvax", "echo", nil); err != nil || url != "ssh://kremvax" {
+	if url, err := resolveServer("kremvax", "echo"); err != nil || url != "ssh://kremvax" {
 		t.Errorf("resolveServer(kremvax) = %q, %v", url, err)
 	}
-	if url, err := resolveServer("tcp://h:1/pw", "echo", nil); err != nil || url != "tcp://h:1/pw" {
+	if url, err := resolveServer("tcp://h:1/pw", "echo"); err != nil || url != "tcp://h:1/pw" {
 		t.Errorf("resolveServer(URL) = %q, %v", url, err)
 	}
 	t.Setenv("MOTE", "kremvax")
-	if url, err := resolveServer("", "echo", nil); err != nil || url != "ssh://kremvax" {
+	if url, err := resolveServer("", "echo"); err != nil || url != "ssh://kremvax" {
 		t.Errorf("resolveServer with $MOTE = %q, %v", url, err)
 	}
 	t.Setenv("MOTE", "")
@@ -713,7 +1205,7 @@ func TestResolveServer(t *testing.T) {
 	if err := setAlias("linux-amd64", "tcp://h:1/pw"); err != nil {
 		t.Fatal(err)
 	}
-	if url, err := resolveServer("", "echo", nil); err != nil || url != "tcp://h:1/pw" {
+	if url, err := resolveServer("", "echo"); err != nil || url != "tcp://h:1/pw" {
 		t.Errorf("resolveServer with $GOOS/$GOARCH = %q, %v", url, err)
 	}
 }
@@ -726,11 +1218,11 @@ func TestResolveServerGomote(t *testing.T) {
 		{"freebsd-amd64", "gomote://gotip-freebsd-amd64_15.0"},
 		{"linux-ppc64", "gomote://gotip-linux-ppc64_power10"},
 	} {
-		if url, err := resolveServer(tt.name, "echo", nil); err != nil || url != tt.want {
+		if url, err := resolveServer(tt.name, "echo"); err != nil || url != tt.want {
 			t.Errorf("resolveServer(%s) = %q, %v; want %q", tt.name, url, err, tt.want)
 		}
 	}
-	if _, err := resolveServer("plan9-386", "echo", nil); err == nil {
+	if _, err := resolveServer("plan9-386", "echo"); err == nil {
 		t.Errorf("resolveServer(plan9-386) succeeded, want error")
 	}
 }
@@ -2,7 +2,7 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-package main
+package mote
 
 import (
 	"debug/buildinfo"
@@ -11,6 +11,7 @@ import (
 	"debug/pe"
 	"encoding/binary"
 	"fmt"
+	"strings"
 )
 
 // binaryOSArch reports the GOOS and GOARCH that the binary file
@@ -99,3 +100,62 @@ func binaryOSArch(file string) (goos, goarch string, err error) {
 	}
 	return "", "", fmt.Errorf("%s: cannot determine GOOS/GOARCH", file)
 }
+
+// binaryLevel returns the microarchitecture level that the Go binary
+// file was built for: its GOAMD64, GOARM64, or GOARM setting, or "" if
+// it is not a Go binary or records no level. See cpulevel.go.
+func binaryLevel(file string) string {
+	bi, err := buildinfo.ReadFile(file)
+	if err != nil {
+		return ""
+	}
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "GOAMD64", "GOARM64", "GOARM":
+			return s.Value
+		}
+	}
+	return ""
+}
+
+// unameOS and unameArch map the operating system and machine names
+// printed by "uname -sm" to GOOS and GOARCH values.
+var (
+	unameOS = map[string]string{
+		"Darwin":    "darwin",
+		"DragonFly": "dragonfly",
+		"FreeBSD":   "freebsd",
+		"Linux":     "linux",
+		"NetBSD":    "netbsd",
+		"OpenBSD":   "openbsd",
+		"SunOS":     "solaris",
+	}
+	unameArch = map[string]string{
+		"aarch64":     "arm64",
+		"amd64":       "amd64",
+		"arm64":       "arm64",
+		"armv6l":      "arm",
+		"armv7l":      "arm",
+		"i386":        "386",
+		"i686":        "386",
+		"i86pc":       "amd64",
+		"loongarch64": "loong64",
+		"mips64":      "mips64",
+		"ppc64":       "ppc64",
+		"ppc64le":     "ppc64le",
+		"riscv64":     "riscv64",
+		"s390x":       "s390x",
+		"x86_64":      "amd64",
+	}
+)
+
+// unameOSArch returns the GOOS and GOARCH of a machine
+// whose "uname -sm" printed out.
+func unameOSArch(out string) (goos, goarch string, err error) {
+	sys, machine, ok := strings.Cut(strings.TrimSpace(out), " ")
+	goos, goarch = unameOS[sys], unameArch[machine]
+	if !ok || goos == "" || goarch == "" {
+		return "", "", fmt.Errorf("cannot determine GOOS/GOARCH from uname %q", strings.TrimSpace(out))
+	}
+	return goos, goarch, nil
+}
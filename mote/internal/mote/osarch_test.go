@@ -2,7 +2,7 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-package main
+package mote
 
 import (
 	"os"
@@ -37,3 +37,22 @@ func TestBinaryOSArch(t *testing.T) {
 		}
 	}
 }
+
+func TestUnameOSArch(t *testing.T) {
+	for _, tt := range []struct{ out, goos, goarch string }{
+		{"Linux x86_64\n", "linux", "amd64"},
+		{"Linux aarch64\n", "linux", "arm64"},
+		{"Darwin arm64\n", "darwin", "arm64"},
+		{"FreeBSD amd64\n", "freebsd", "amd64"},
+		{"Linux armv7l\n", "linux", "arm"},
+		{"SunOS i86pc\n", "solaris", "amd64"},
+		{"Linux\n", "", ""},
+		{"Plan9 386\n", "", ""},
+		{"Linux vax\n", "", ""},
+	} {
+		goos, goarch, err := unameOSArch(tt.out)
+		if goos != tt.goos || goarch != tt.goarch || (err != nil) != (tt.goos == "") {
+			t.Errorf("unameOSArch(%q) = %s, %s, %v, want %s, %s", tt.out, goos, goarch, err, tt.goos, tt.goarch)
+		}
+	}
+}
@@ -0,0 +1,325 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mote
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"time"
+)
+
+// A Request is the JSON metadata sent from client to server.
+// See ../../protocol.md.
+type Request struct {
+	Type      string
+	Error     string   `json:",omitzero"`
+	Files     []*File  `json:",omitzero"`
+	Args      []string `json:",omitzero"`
+	Dir       string   `json:",omitzero"`
+	Env       []string `json:",omitzero"`
+	Addr      string   `json:",omitzero"` // Dial, to the Tailscale daemon; Open: address to connect to
+	Stdin     bool     `json:",omitzero"` // Setup: Stdin requests will follow Start
+	Exclusive bool     `json:",omitzero"` // Setup: wait for sole use of the server
+	Link      string   `json:",omitzero"` // Setup: how to place cached files (see copyFromCache)
+	Listen    []string `json:",omitzero"` // Setup: ports for the server to forward to the client (see forward.go)
+	Core      bool     `json:",omitzero"` // Setup: send back a core dump if the command crashes (see core.go)
+	PathEnv   []string `json:",omitzero"` // Setup: environment variables naming client paths, to map into the tree (see mapPathEnv)
+	Drain     bool     `json:",omitzero"` // Setup: send a Draining response if the server shuts down while the command runs (see drain.go)
+	Chan      int      `json:",omitzero"` // Open, Data, Close: forwarded connection
+}
+
+// A File describes a file to be placed on the remote system.
+type File struct {
+	Path string
+	Hash string
+	Size int64
+}
+
+// A Response is the JSON metadata sent from server to client.
+// See ../../protocol.md.
+type Response struct {
+	Type       string
+	Error      string        `json:",omitempty"`
+	Need       []string      `json:",omitempty"`
+	Stderr     bool          `json:",omitzero"`
+	ExitCode   int           `json:",omitzero"`
+	Status     string        `json:",omitzero"`
+	GOOS       string        `json:",omitzero"`
+	GOARCH     string        `json:",omitzero"`
+	Waited     time.Duration `json:",omitzero"` // Exclusive: time spent waiting
+	Version    int           `json:",omitzero"` // Info: protocol version
+	Caps       []string      `json:",omitzero"` // Info: optional features supported
+	Load       int           `json:",omitzero"` // Info: commands in progress on the server (see load.go)
+	Level      string        `json:",omitzero"` // Info: highest microarchitecture level of the CPU (see cpulevel.go)
+	Details    *Details      `json:",omitzero"` // Info, answering an Info request: the machine in detail (see info.go)
+	Peers      []string      `json:",omitzero"` // Peers, from the Tailscale daemon: mote nodes on the tailnet
+	Chan       int           `json:",omitzero"` // Open, Data, Close: forwarded connection (see forward.go)
+	Addr       string        `json:",omitzero"` // Open: port the connection arrived on
+	WallTime   time.Duration `json:",omitzero"` // Exit: time the command ran
+	UserTime   time.Duration `json:",omitzero"` // Exit: user CPU time
+	SystemTime time.Duration `json:",omitzero"` // Exit: system CPU time
+	MaxRSS     int64         `json:",omitzero"` // Exit: maximum resident set size, in bytes
+	Output     int64         `json:",omitzero"` // Exit: bytes of standard output and standard error
+	Core       string        `json:",omitzero"` // Core: which file the data belongs to, "core" or "exe" (see core.go)
+	Grace      time.Duration `json:",omitzero"` // Draining: time the command has left to finish before it is killed
+}
+
+// protocolVersion is the version of the protocol spoken by this mote.
+// The server reports its version in the Info response; a server that
+// reports none predates versioning and speaks version 0.
+// Each new version adds to the one before it, so a client can always
+// talk to an older server, skipping only what that server cannot do.
+const protocolVersion = 7
+
+// Capabilities name optional protocol features, which a server lists
+// in the Info response. A client asked to use a feature the server
+// does not list runs the command without it, with a warning.
+// See ../../protocol.md.
+const (
+	capStdin     = "stdin"     // Stdin requests
+	capExclusive = "exclusive" // Setup Exclusive field
+	capLink      = "link"      // Setup Link field
+	capForward   = "forward"   // Setup Listen field and Open, Data, Close
+	capStats     = "stats"     // Exit resource usage fields
+	capInfo      = "info"      // Info requests
+	capCore      = "core"      // Setup Core field and Core responses
+	capPathEnv   = "pathenv"   // Setup PathEnv field
+	capDrain     = "drain"     // Setup Drain field and Draining responses
+)
+
+// allCaps lists the capabilities this mote implements.
+var allCaps = []string{capStdin, capExclusive, capLink, capForward, capStats, capInfo, capCore, capPathEnv, capDrain}
+
+// serverVersion and serverCaps are what this server reports in Info.
+// They are variables for testing, to simulate older servers.
+var (
+	serverVersion = protocolVersion
+	serverCaps    = allCaps
+)
+
+// maxJSON is the maximum accepted size for the JSON section of a packet.
+// The binary section is unlimited (uploads can be arbitrarily large),
+// but the JSON metadata should always be small.
+const maxJSON = 1 << 20
+
+// maxHandshake is the maximum accepted size for a handshake packet.
+// The CPace messages, the key confirmation tags, and the Noise messages
+// are all well under 128 bytes.
+const maxHandshake = 1024
+
+// A Conn is a mote protocol connection, reading and writing framed
+// packets on an underlying stream.
+// Each packet is a 32-bit big-endian JSON length, a 32-bit big-endian
+// binary data length, the JSON, and then the binary data.
+//
+// On the client, GOOS and GOARCH record the server's operating system
+// and architecture, Level its CPU's microarchitecture level, Version
+// and Caps its protocol version and capabilities, and Load its load
+// when the connection was made, from the Info response read by
+// dialServer.
+//
+// If $MOTETRACE is set, a Conn records the packets it sends and
+// receives there; see trace.go.
+//
+// A Conn reads only the exact bytes of each packet (no buffering).
+// The encryption handshake messages travel as packets on the plaintext
+// stream, and then a new Conn is created on top of the encrypted
+// stream; exact reads mean no bytes are lost to a buffer during that
+// switch.
+type Conn struct {
+	GOOS    string
+	GOARCH  string
+	Level   string
+	Version int
+	Caps    []string
+	Load    int
+	rw      io.ReadWriteCloser
+	wmu     sync.Mutex
+	trace   *connTrace
+}
+
+func newConn(rw io.ReadWriteCloser) *Conn {
+	return &Conn{rw: rw, trace: newConnTrace()}
+}
+
+func (c *Conn) Close() error {
+	return c.rw.Close()
+}
+
+// has reports whether the server lists the capability name.
+func (c *Conn) has(name string) bool {
+	return slices.Contains(c.Caps, name)
+}
+
+// current reports whether the server speaks this mote's protocol
+// version (or a later one) and supports all of its capabilities.
+func (c *Conn) current() bool {
+	if c.Version < protocolVersion {
+		return false
+	}
+	for _, name := range allCaps {
+		if !c.has(name) {
+			return false
+		}
+	}
+	return true
+}
+
+// writePacket writes a packet with the JSON encoding of js
+// (or no JSON at all if js is nil) followed by the binary data.
+func (c *Conn) writePacket(js any, data []byte) error {
+	var enc []byte
+	if js != nil {
+		var err error
+		enc, err = json.Marshal(js)
+		if err != nil {
+			return err
+		}
+	}
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	c.trace.record("send", enc, int64(len(data)), data)
+	var hdr [8]byte
+	binary.BigEndian.PutUint32(hdr[0:], uint32(len(enc)))
+	binary.BigEndian.PutUint32(hdr[4:], uint32(len(data)))
+	if _, err := c.rw.Write(hdr[:]); err != nil {
+		return err
+	}
+	// Skip zero-length writes: the reader does not issue a Read for an
+	// empty section, and a zero-length Write blocks on net.Pipe.
+	if len(enc) > 0 {
+		if _, err := c.rw.Write(enc); err != nil {
+			return err
+		}
+	}
+	if len(data) > 0 {
+		if _, err := c.rw.Write(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writePacketStream writes a packet whose binary data is size bytes
+// copied from r. It is an error for r to have fewer or more than
+// size bytes.
+func (c *Conn) writePacketStream(js any, size int64, r io.Reader) error {
+	var enc []byte
+	if js != nil {
+		var err error
+		enc, err = json.Marshal(js)
+		if err != nil {
+			return err
+		}
+	}
+	if int64(uint32(size)) != size {
+		return fmt.Errorf("data too large for packet")
+	}
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	c.trace.record("send", enc, size, nil)
+	var hdr [8]byte
+	binary.BigEndian.PutUint32(hdr[0:], uint32(len(enc)))
+	binary.BigEndian.PutUint32(hdr[4:], uint32(size))
+	if _, err := c.rw.Write(hdr[:]); err != nil {
+		return err
+	}
+	if len(enc) > 0 {
+		if _, err := c.rw.Write(enc); err != nil {
+			return err
+		}
+	}
+	n, err := io.CopyN(c.rw, r, size)
+	if err == io.EOF {
+		return fmt.Errorf("short data stream: %d bytes copied, want %d", n, size)
+	}
+	if err != nil {
+		return err
+	}
+	var extra [1]byte
+	if n, _ := r.Read(extra[:]); n > 0 {
+		return fmt.Errorf("data stream longer than %d bytes", size)
+	}
+	return nil
+}
+
+// readPacket reads a packet, decoding the JSON section into js
+// (which should be a pointer) and returning the binary data.
+func (c *Conn) readPacket(js any) ([]byte, error) {
+	size, body, err := c.readPacketStream(js)
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(body, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// readHandshakePacket reads a handshake packet: a packet with no JSON
+// section and at most maxHandshake bytes of data.
+//
+// Handshake packets arrive before the peer has been authenticated, so
+// unlike readPacket, which trusts the packet header enough to allocate a
+// buffer for an upload of any size, readHandshakePacket checks the size
+// before allocating. Otherwise any peer that can connect to the server
+// could make it allocate up to 4 GB by sending an eight-byte header.
+func (c *Conn) readHandshakePacket() ([]byte, error) {
+	var hdr [8]byte
+	if _, err := io.ReadFull(c.rw, hdr[:]); err != nil {
+		return nil, err
+	}
+	jsize := binary.BigEndian.Uint32(hdr[0:])
+	dsize := binary.BigEndian.Uint32(hdr[4:])
+	if jsize != 0 {
+		return nil, fmt.Errorf("handshake packet has JSON section")
+	}
+	if dsize > maxHandshake {
+		return nil, fmt.Errorf("handshake packet too large: %d bytes", dsize)
+	}
+	c.trace.record("recv", nil, int64(dsize), nil)
+	data := make([]byte, dsize)
+	if _, err := io.ReadFull(c.rw, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// readPacketStream reads a packet header and JSON section, decoding the
+// JSON into js, and returns a reader for the binary data section.
+// The caller must fully read the returned reader before the next
+// call to readPacket or readPacketStream.
+func (c *Conn) readPacketStream(js any) (size int64, body io.Reader, err error) {
+	var hdr [8]byte
+	if _, err := io.ReadFull(c.rw, hdr[:]); err != nil {
+		return 0, nil, err
+	}
+	jsize := binary.BigEndian.Uint32(hdr[0:])
+	dsize := binary.BigEndian.Uint32(hdr[4:])
+	if jsize > maxJSON {
+		return 0, nil, fmt.Errorf("packet JSON too large: %d bytes", jsize)
+	}
+	var enc []byte
+	if jsize > 0 {
+		enc = make([]byte, jsize)
+		if _, err := io.ReadFull(c.rw, enc); err != nil {
+			return 0, nil, err
+		}
+	}
+	// Record the packet before decoding it: a packet that fails
+	// to decode is just what a trace is for.
+	c.trace.record("recv", enc, int64(dsize), nil)
+	if jsize > 0 && js != nil {
+		if err := json.Unmarshal(enc, js); err != nil {
+			return 0, nil, err
+		}
+	}
+	return int64(dsize), io.LimitReader(c.rw, int64(dsize)), nil
+}
@@ -0,0 +1,16 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !linux
+
+package mote
+
+import "net"
+
+// peerUID reports that mote does not know how to find out who is at
+// the other end of a Unix domain socket connection on this system.
+// The socket file's permissions still keep other users out.
+func peerUID(c net.Conn) (int, error) {
+	return 0, errUnsupported
+}
@@ -0,0 +1,125 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mote
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// The qemu transport runs programs for other Linux architectures on
+// this machine, under QEMU's user-mode emulation, for quick checks
+// when no real server is at hand: "mote @qemu://linux-riscv64
+// ./pkg.test". There is no remote end. The client serves the session
+// itself, in process, over a pipe, so the upload, cache, and temporary
+// tree are exactly those of any other server, and relative testdata
+// paths behave the same way.
+//
+// Uploaded programs run under the emulator. If binfmt_misc has the
+// emulator registered for the architecture, the kernel runs it for
+// every program for that architecture, which is the better setup:
+// programs that run others (a test binary running itself, say) keep
+// working. Otherwise the server runs the emulator (qemu-riscv64 or
+// qemu-riscv64-static) on the program. Programs found on $PATH, rather
+// than uploaded, are this machine's own and run natively.
+
+// An emulator describes a system whose programs run under emulation.
+type emulator struct {
+	goos, goarch string
+	qemu         string // emulator to run programs under, or "" to run them directly
+}
+
+// command returns the command running the program file with args,
+// where args[0] is the name the program should see as its own.
+func (e *emulator) command(file string, args []string) *exec.Cmd {
+	if e.qemu == "" {
+		c := exec.Command(file)
+		c.Args = args
+		return c
+	}
+	return exec.Command(e.qemu, append([]string{"-0", args[0], file}, args[1:]...)...)
+}
+
+// qemuArch maps GOARCH values to QEMU's names for the architectures.
+var qemuArch = map[string]string{
+	"386":      "i386",
+	"amd64":    "x86_64",
+	"arm":      "arm",
+	"arm64":    "aarch64",
+	"loong64":  "loongarch64",
+	"mips":     "mips",
+	"mipsle":   "mipsel",
+	"mips64":   "mips64",
+	"mips64le": "mips64el",
+	"ppc64":    "ppc64",
+	"ppc64le":  "ppc64le",
+	"riscv64":  "riscv64",
+	"s390x":    "s390x",
+}
+
+// binfmtDir is where the kernel lists the binfmt_misc registrations.
+// It is a variable for testing.
+var binfmtDir = "/proc/sys/fs/binfmt_misc"
+
+// findEmulator returns the emulator for goos-goarch programs on this
+// machine, or an error explaining why there is none.
+func findEmulator(goos, goarch string) (*emulator, error) {
+	if runtime.GOOS != "linux" {
+		return nil, fmt.Errorf("qemu:// needs a Linux machine to run on")
+	}
+	if goos != "linux" {
+		return nil, fmt.Errorf("qemu:// runs only linux programs, not %s", goos)
+	}
+	e := &emulator{goos: goos, goarch: goarch}
+	if goarch == runtime.GOARCH {
+		return e, nil // no emulation needed
+	}
+	arch, ok := qemuArch[goarch]
+	if !ok {
+		return nil, fmt.Errorf("qemu:// does not know GOARCH=%s", goarch)
+	}
+	if data, err := os.ReadFile(filepath.Join(binfmtDir, "qemu-"+arch)); err == nil && strings.HasPrefix(string(data), "enabled\n") {
+		return e, nil
+	}
+	for _, name := range []string{"qemu-" + arch, "qemu-" + arch + "-static"} {
+		if qemu, err := exec.LookPath(name); err == nil {
+			e.qemu = qemu
+			return e, nil
+		}
+	}
+	return nil, fmt.Errorf("qemu:// needs qemu-%s installed (or registered with binfmt_misc) to run linux-%s programs", arch, goarch)
+}
+
+// qemuFallback reports whether a goos-goarch name with no alias falls
+// back to qemu://: only for another architecture that this machine can
+// emulate. A program for this machine needs no emulation, and running
+// it here would quietly stand in for whatever server was meant.
+func qemuFallback(goos, goarch string) bool {
+	if goos == runtime.GOOS && goarch == runtime.GOARCH {
+		return false
+	}
+	_, err := findEmulator(goos, goarch)
+	return err == nil
+}
+
+// dialQemu connects to a qemu://linux-goarch server, which is a
+// session served in this process over an in-memory pipe, like local://.
+func dialQemu(u *url.URL) (io.ReadWriteCloser, error) {
+	goos, goarch, ok := strings.Cut(u.Host, "-")
+	if !ok || u.Path != "" {
+		return nil, fmt.Errorf("invalid server URL %s: want qemu://goos-goarch", u)
+	}
+	emu, err := findEmulator(goos, goarch)
+	if err != nil {
+		return nil, err
+	}
+	return servePipe(emu), nil
+}
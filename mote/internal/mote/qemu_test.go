@@ -0,0 +1,146 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mote
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// qemuMockMain stands in for qemu-riscv64, running the program natively
+// with $MOTE_TEST_QEMU set, so that tests can see it ran "emulated".
+func qemuMockMain() {
+	log.SetPrefix("qemu mock: ")
+	log.SetFlags(0)
+	if len(os.Args) < 4 || os.Args[1] != "-0" {
+		log.Fatalf("bad args: %q", os.Args)
+	}
+	c := exec.Command(os.Args[3], os.Args[4:]...)
+	c.Args[0] = os.Args[2]
+	c.Env = append(os.Environ(), "MOTE_TEST_QEMU=riscv64")
+	c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+	err := c.Run()
+	var exit *exec.ExitError
+	if errors.As(err, &exit) {
+		os.Exit(exit.ExitCode())
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	os.Exit(0)
+}
+
+func TestQemuTransport(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("qemu:// needs linux")
+	}
+	setupDirs(t)
+	binfmtDir = t.TempDir()
+	defer func() { binfmtDir = "/proc/sys/fs/binfmt_misc" }()
+
+	// No emulator installed: no qemu server.
+	t.Setenv("PATH", t.TempDir())
+	if _, err := dialServer("qemu://linux-riscv64"); err == nil || !strings.Contains(err.Error(), "qemu-riscv64") {
+		t.Fatalf("dialServer without qemu: %v, want error naming qemu-riscv64", err)
+	}
+	if _, err := dialServer("qemu://windows-amd64"); err == nil {
+		t.Fatalf("dialServer(qemu://windows-amd64) succeeded")
+	}
+	if _, err := resolveServer("linux-riscv64", "echo"); err == nil {
+		t.Fatalf("resolveServer(linux-riscv64) without qemu succeeded")
+	}
+
+	// With one, uploaded programs run under it, in the usual tree,
+	// and commands from $PATH run natively.
+	mockPATH(t, "qemu-riscv64")
+	t.Setenv("PATH", os.Getenv("PATH")+string(os.PathListSeparator)+"/bin:/usr/bin")
+	if url, err := resolveServer("linux-riscv64", "echo"); err != nil || url != "qemu://linux-riscv64" {
+		t.Fatalf("resolveServer(linux-riscv64) = %q, %v, want qemu://linux-riscv64", url, err)
+	}
+	// This machine's own GOOS-GOARCH is no guess at a server to emulate.
+	if url, err := resolveServer("linux-"+runtime.GOARCH, "echo"); err == nil {
+		t.Errorf("resolveServer(linux-%s) = %q, want error", runtime.GOARCH, url)
+	}
+	dir := t.TempDir()
+	script := filepath.Join(dir, "x.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\necho emulated $MOTE_TEST_QEMU; cat testdata/x\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "testdata"), 0o777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "testdata", "x"), []byte("data\n"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	var files []*File
+	if err := addTree(&files, dir, nil); err != nil {
+		t.Fatal(err)
+	}
+	conn, err := dialServer("qemu://linux-riscv64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if conn.GOOS != "linux" || conn.GOARCH != "riscv64" {
+		t.Errorf("server is %s-%s, want linux-riscv64", conn.GOOS, conn.GOARCH)
+	}
+	var outb, errb bytes.Buffer
+	w, err := conn.Run(t.Context(), &Exec{Args: []string{"./x.sh"}, Dir: filepath.ToSlash(dir), Files: files, Stdout: &outb, Stderr: &errb})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "emulated riscv64\ndata\n"; w.Code != 0 || outb.String() != want {
+		t.Errorf("code=%d stdout=%q stderr=%q, want 0, %q", w.Code, outb.String(), errb.String(), want)
+	}
+
+	conn, err = dialServer("qemu://linux-riscv64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	runConn(t, conn, []string{"sh", "-c", "echo native $MOTE_TEST_QEMU"}, "native\n")
+
+	// A command run on the guessed server does not save the guess.
+	mockPATH(t, "mote")
+	if out, err := exec.Command("mote", "@linux-riscv64", "sh", "-c", "true").CombinedOutput(); err != nil {
+		t.Fatalf("mote @linux-riscv64: %v\n%s", err, out)
+	}
+	if url, err := lookupAlias("linux-riscv64"); err != nil || url != "" {
+		t.Errorf("alias linux-riscv64 = %q, %v after a guessed run; want none", url, err)
+	}
+}
+
+func TestFindEmulatorBinfmt(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("qemu:// needs linux")
+	}
+	binfmtDir = t.TempDir()
+	defer func() { binfmtDir = "/proc/sys/fs/binfmt_misc" }()
+	t.Setenv("PATH", t.TempDir())
+	if err := os.WriteFile(filepath.Join(binfmtDir, "qemu-s390x"), []byte("enabled\ninterpreter /usr/bin/qemu-s390x\n"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(binfmtDir, "qemu-ppc64le"), []byte("disabled\n"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	// A registered emulator runs programs directly.
+	if e, err := findEmulator("linux", "s390x"); err != nil || e.qemu != "" {
+		t.Errorf("findEmulator(s390x) = %+v, %v, want binfmt_misc", e, err)
+	}
+	if _, err := findEmulator("linux", "ppc64le"); err == nil {
+		t.Errorf("findEmulator(ppc64le) succeeded with binfmt_misc entry disabled")
+	}
+	// So do programs for this machine.
+	if e, err := findEmulator("linux", runtime.GOARCH); err != nil || e.qemu != "" {
+		t.Errorf("findEmulator(%s) = %+v, %v, want native", runtime.GOARCH, e, err)
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mote
+
+import (
+	"errors"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Exclusive use of the server machine, for benchmarking.
+//
+// Every command holds a reservation on the machine while it runs:
+// a shared one ordinarily, and an exclusive one when the client asks
+// for it. An exclusive reservation waits for the running commands to
+// finish and keeps new ones from starting until it is released.
+//
+// Sessions served by one process (tcp:// and tail://) are ordered by
+// machineLock, whose writer preference also keeps a steady stream of
+// ordinary commands from starving an exclusive one. Sessions in
+// separate processes (each ssh:// or gomote:// session is its own
+// "mote serve -") are ordered by a file lock in the cache directory,
+// which all servers running as the same user share.
+
+// machineLock orders the reservations of the sessions in this process.
+var machineLock sync.RWMutex
+
+// errKilled is reported by reserveMachine when the client kills the
+// command, or hangs up, before the reservation is granted.
+var errKilled = errors.New("killed before start")
+
+// reservePath returns the name of the file lock ordering reservations
+// between processes.
+func reservePath() (string, error) {
+	dir, err := cacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "reserve.lock"), nil
+}
+
+// reserveMachine waits for a reservation on the machine, exclusive or
+// shared, returning a function that releases it (and may be called more
+// than once) and how long the wait took. If cancel is closed first, it
+// gives up and returns errKilled.
+func reserveMachine(exclusive bool, cancel <-chan struct{}) (func(), time.Duration, error) {
+	start := time.Now()
+	var release func()
+	var err error
+	done := make(chan struct{})
+	go func() {
+		release, err = acquireMachine(exclusive)
+		close(done)
+	}()
+	select {
+	case <-done:
+		return release, time.Since(start), err
+	case <-cancel:
+		// The wait cannot be interrupted; release the reservation
+		// whenever it arrives, so that it does not stay held.
+		go func() {
+			<-done
+			if release != nil {
+				release()
+			}
+		}()
+		return nil, 0, errKilled
+	}
+}
+
+// acquireMachine waits for the in-process and then the cross-process
+// reservation, in that order, so that the file lock is only ever
+// contended between processes.
+func acquireMachine(exclusive bool) (release func(), err error) {
+	lock, unlock := machineLock.RLock, machineLock.RUnlock
+	if exclusive {
+		lock, unlock = machineLock.Lock, machineLock.Unlock
+	}
+	path, err := reservePath()
+	if err != nil {
+		return nil, err
+	}
+	lock()
+	f, err := waitLockFile(path, exclusive)
+	if err != nil {
+		unlock()
+		return nil, err
+	}
+	return sync.OnceFunc(func() {
+		if f != nil {
+			f.Close()
+		}
+		unlock()
+	}), nil
+}
@@ -2,7 +2,7 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-package main
+package mote
 
 import (
 	"crypto/hkdf"
@@ -21,7 +21,7 @@ import (
 // and then a Noise NNpsk0 handshake using that key as the pre-shared key
 // establishes the encrypted channel.
 // The handshake messages travel in the standard packet framing
-// with no JSON section. See protocol.md.
+// with no JSON section. See ../../protocol.md.
 
 // channelID is the CPace channel identifier, fixed for the mote protocol.
 var channelID = []byte("rsc.io/cmd/mote tcp")
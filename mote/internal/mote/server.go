@@ -0,0 +1,664 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mote
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// cmdServe implements "mote serve URL".
+//
+// Along with the URLs that name a server, it accepts "-", to serve on
+// standard input and output, and "-hex-", to serve there in hex (see
+// hex.go). The hex form is spelled as a URL rather than a flag because
+// nothing but mote itself has any reason to ask for it; serve parses
+// no flags, so the leading dash costs nothing.
+func cmdServe(args []string) {
+	if len(args) != 1 {
+		usage()
+	}
+	url := args[0]
+	if *metricsAddr != "" && (url == "-" || url == "-hex-") {
+		// Such a server runs one session and exits: nothing to watch.
+		log.Fatalf("-metrics requires a tcp://, tail://, unix://, or ws:// server")
+	}
+	switch {
+	case url == "-":
+		if err := serve(stdioConn{}, "", nil); err != nil {
+			log.Fatal(err)
+		}
+	case url == "-hex-":
+		if err := serve(serveHex(), "", nil); err != nil {
+			log.Fatal(err)
+		}
+	case strings.HasPrefix(url, "tcp://"):
+		serveTCP(url)
+	case strings.HasPrefix(url, "tail:"):
+		serveTail(url)
+	case strings.HasPrefix(url, "unix:"):
+		serveUnix(url)
+	case strings.HasPrefix(url, "ws:"), strings.HasPrefix(url, "wss:"):
+		serveWS(url)
+	default:
+		log.Fatalf("cannot serve %s", url)
+	}
+}
+
+// serveHex prepares standard input and output for a hex-encoded
+// session: it takes the terminal, if there is one, out of cooked mode,
+// so that it stops echoing back what the client writes and stops
+// rewriting the bytes passing through; it writes the handshake line
+// that tells the client hex encoding is beginning; and it returns the
+// encoded connection to serve. See hex.go.
+func serveHex() io.ReadWriteCloser {
+	if _, err := makeStdinRaw(); err != nil {
+		// Report and keep going: the terminal may already be raw, and
+		// if it is not, the client reports the resulting mangled data.
+		// This lands ahead of the handshake line, where the client
+		// treats it as preamble.
+		fmt.Fprintf(os.Stderr, "mote: %v\n", err)
+	}
+	os.Stdout.WriteString(hexHandshake)
+	return newHexConn(stdioConn{})
+}
+
+// stdioConn is an io.ReadWriteCloser for serving on standard input and output.
+type stdioConn struct{}
+
+func (stdioConn) Read(p []byte) (int, error)  { return os.Stdin.Read(p) }
+func (stdioConn) Write(p []byte) (int, error) { return os.Stdout.Write(p) }
+func (stdioConn) Close() error                { return nil }
+
+// maxSessions is the maximum number of sessions served at once.
+// The limit bounds the resources that clients, which are unauthenticated
+// until their handshakes finish, can tie up. Connections beyond the limit
+// wait in the listener's queue.
+const maxSessions = 64
+
+// maxUploadTries is how many times a session asks for the same files
+// that cleanCache keeps deleting from the cache before they are placed,
+// as it can when $MOTECACHESIZE is smaller than the files.
+const maxUploadTries = 3
+
+// serveListener accepts connections on ln and serves a session on each,
+// using password to encrypt the session (or "" for transports that are
+// already secure) and env as the base environment for the commands it
+// runs (or nil for this process's environment).
+//
+// SIGTERM or SIGINT drains the server (see drain.go): serveListener
+// stops accepting connections, lets the running commands finish, for
+// up to the -drain time, and returns nil once every session has ended.
+// A second signal kills the commands still running. Otherwise it
+// returns when ln is closed.
+func serveListener(ln net.Listener, password string, env []string) error {
+	return serveDraining(func() { ln.Close() }, func() error {
+		return serveSessions(ln, password, env)
+	})
+}
+
+// serveDraining calls serve, which serves sessions until stop is called
+// and returns once they have all ended, and drains the sessions on
+// SIGTERM or SIGINT, as serveListener describes. stop must not block.
+func serveDraining(stop func(), serve func() error) error {
+	sig := make(chan os.Signal, 2)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sig)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case s := <-sig:
+			log.Printf("%v: draining sessions for up to %v", s, *drainTime)
+			serverDrain.start(*drainTime)
+			stop()
+		case <-done:
+			return
+		}
+		select {
+		case s := <-sig:
+			log.Printf("%v: killing running commands", s)
+			serverDrain.expire()
+		case <-done:
+		}
+	}()
+	err := serve()
+	if serverDrain.started() {
+		log.Printf("drained")
+		return nil
+	}
+	return err
+}
+
+// serveSessions is serveListener without the signal handling, for a
+// process that stops its listener itself: it accepts connections on ln
+// and serves a session on each until ln is closed, and then waits for
+// the sessions to end.
+func serveSessions(ln net.Listener, password string, env []string) error {
+	sem := make(chan struct{}, maxSessions)
+	var sessions sync.WaitGroup
+	defer sessions.Wait()
+	var delay time.Duration
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return err
+			}
+			// Transient failure, such as running out of file descriptors.
+			// Back off and keep serving instead of killing the server:
+			// otherwise a flood of connections could shut it down.
+			delay = min(max(2*delay, 5*time.Millisecond), time.Second)
+			log.Printf("accept: %v (retrying in %v)", err, delay)
+			time.Sleep(delay)
+			continue
+		}
+		delay = 0
+		sem <- struct{}{}
+		sessions.Add(1)
+		go func() {
+			defer sessions.Done()
+			defer func() { <-sem }()
+			defer conn.Close()
+			if err := serve(conn, password, env); err != nil {
+				log.Print(err)
+			}
+		}()
+	}
+}
+
+// serve runs one server session on rw: handshake, optional encryption,
+// setup and file upload, command execution, output streaming, exit status.
+// It is the entire server; every transport ends up here.
+// The command runs with env as its base environment, or this process's
+// environment if env is nil. It does not close rw.
+func serve(rw io.ReadWriteCloser, password string, env []string) error {
+	return serveEmulated(rw, password, env, nil)
+}
+
+// serveEmulated is serve for a server that runs the uploaded programs
+// under the emulator emu, reporting emu's system as its own, or, if emu
+// is nil, runs them natively. See qemu.go.
+func serveEmulated(rw io.ReadWriteCloser, password string, env []string, emu *emulator) error {
+	goos, goarch, level := runtime.GOOS, runtime.GOARCH, cpuLevel()
+	if emu != nil {
+		// The emulator's CPU is not this one; report no level.
+		goos, goarch, level = emu.goos, emu.goarch, ""
+	}
+	serverMetrics.sessions.Add(1)
+	serverMetrics.active.Add(1)
+	defer serverMetrics.active.Add(-1)
+
+	// Bound how long an unauthenticated peer can hold the connection.
+	// The deadline is cleared once the session is established, because
+	// the commands that follow can take arbitrarily long.
+	deadline, _ := rw.(deadlineConn)
+	if deadline != nil {
+		deadline.SetDeadline(time.Now().Add(handshakeTimeout))
+	}
+	if err := serverHandshake(rw); err != nil {
+		serverMetrics.handshakeFailed(err)
+		return err
+	}
+	if password != "" {
+		s, err := secureServer(rw, password)
+		if err != nil {
+			serverMetrics.handshakeFailed(err)
+			return err
+		}
+		rw = s
+	}
+	if deadline != nil {
+		deadline.SetDeadline(time.Time{})
+	}
+	conn := newConn(rw)
+
+	info := &Response{Type: "Info", GOOS: goos, GOARCH: goarch, Level: level, Version: serverVersion, Caps: serverCaps, Load: machineLoad()}
+	if err := conn.writePacket(info, nil); err != nil {
+		return err
+	}
+	drain := watchDrain(serverDrain, conn, rw)
+	defer drain.stop()
+	fail := func(format string, args ...any) error {
+		err := fmt.Errorf(format, args...)
+		serverMetrics.setupFails.Add(1)
+		conn.writePacket(&Response{Type: "Exit", Error: err.Error()}, nil)
+		return err
+	}
+
+	var req Request
+	if _, err := conn.readPacket(&req); err != nil {
+		if err == io.EOF {
+			// The client hung up after reading Info, as a client
+			// choosing among a group of servers does with the ones
+			// it does not choose. That is not an error.
+			return nil
+		}
+		return fmt.Errorf("reading request: %v", err)
+	}
+	if req.Type == "Info" {
+		// mote info: describe the machine, as the client's commands
+		// would see it, and that is the session.
+		base := env
+		if base == nil {
+			base = os.Environ()
+		}
+		info.Details = describe(slices.Concat(base, req.Env))
+		return conn.writePacket(info, nil)
+	}
+	if req.Type != "Setup" {
+		return fail("unexpected request type %q", req.Type)
+	}
+	if len(req.Args) == 0 || !validLink(req.Link) {
+		return fail("malformed Setup request")
+	}
+	defer markBusy()()
+
+	sizes := make(map[string]int64)
+	for _, f := range req.Files {
+		if !validHash(f.Hash) || f.Size < 0 {
+			return fail("malformed file %q in Setup request", f.Path)
+		}
+		sizes[f.Hash] = f.Size
+	}
+
+	// Reconstruct the directory tree in a temporary directory.
+	tmpdir, err := os.MkdirTemp("", "mote-")
+	if err != nil {
+		return fail("%v", err)
+	}
+	defer os.RemoveAll(tmpdir)
+	// A command that names a path names one of the uploaded files, and
+	// what runs is that file's copy in the temporary tree: exec would
+	// resolve a relative name against Dir, but an absolute name is a
+	// path on the client ("go test" runs its test binaries by absolute
+	// path), so both are mapped the way the file itself was. On Windows
+	// the copy may also need a name that Windows will run; see exeName.
+	cmd := clientPath(req.Dir, req.Args[0])
+	name := req.Args[0]
+	uploaded := false
+	place := func(f *File, again bool) (bool, error) {
+		dst, err := remotePath(tmpdir, f.Path)
+		if err != nil {
+			return false, err
+		}
+		// Hold the cache from finding the file there until it has been
+		// copied out, so that no cleanCache, in this server or another,
+		// deletes it in between, but no longer: cleaning may go on while
+		// this session waits for its uploads.
+		releaseCache, err := holdCache()
+		if err != nil {
+			return false, err
+		}
+		defer releaseCache()
+		found := inCache
+		if again {
+			found = cached // already counted as a miss
+		}
+		if !found(f.Hash, f.Size) {
+			return false, nil
+		}
+		if f.Path == cmd {
+			file, err := cacheFile(f.Hash)
+			if err != nil {
+				return false, err
+			}
+			dst = exeName(goos, dst, file)
+			name = dst
+			uploaded = true
+		}
+		return true, copyFromCache(f.Hash, dst, req.Link)
+	}
+
+	// Place the files the cache has and ask for the rest, until every
+	// file is placed. A file cleaned out of the cache after its upload
+	// and before its placing is asked for again, a few times at most.
+	pending := req.Files
+	for try := 0; len(pending) > 0; try++ {
+		var missing []*File
+		var need []string
+		seen := make(map[string]bool)
+		for _, f := range pending {
+			if seen[f.Hash] {
+				missing = append(missing, f) // already asked for
+				continue
+			}
+			ok, err := place(f, try > 0)
+			if err != nil {
+				return fail("%v", err)
+			}
+			if !ok {
+				missing = append(missing, f)
+				seen[f.Hash] = true
+				need = append(need, f.Hash)
+			}
+		}
+		if len(missing) == 0 {
+			break
+		}
+		if try == maxUploadTries {
+			return fail("cache cleaned out uploaded files before they could be used; is $MOTECACHESIZE too small?")
+		}
+		if err := conn.writePacket(&Response{Type: "Need", Need: need}, nil); err != nil {
+			return err
+		}
+		var up Request
+		size, body, err := conn.readPacketStream(&up)
+		if err != nil {
+			return fmt.Errorf("reading upload: %v", err)
+		}
+		if up.Type != "Upload" {
+			return fail("unexpected request type %q during upload", up.Type)
+		}
+		var want int64
+		for _, hash := range need {
+			want += sizes[hash]
+		}
+		if size != want {
+			return fail("upload size %d does not match requested %d", size, want)
+		}
+		for _, hash := range need {
+			if err := saveToCache(hash, sizes[hash], body); err != nil {
+				return fail("%v", err)
+			}
+		}
+		serverMetrics.uploadBytes.Add(size)
+		pending = missing
+	}
+	dir, err := remotePath(tmpdir, req.Dir)
+	if err != nil {
+		return fail("%v", err)
+	}
+	if err := os.MkdirAll(dir, 0o777); err != nil {
+		return fail("%v", err)
+	}
+	pathEnv, err := mapPathEnv(tmpdir, req.PathEnv)
+	if err != nil {
+		return fail("%v", err)
+	}
+
+	// Everything is in place; wait for the Start request.
+	if err := conn.writePacket(&Response{Type: "Ready"}, nil); err != nil {
+		return err
+	}
+	var start Request
+	if _, err := conn.readPacket(&start); err != nil {
+		return fmt.Errorf("reading request: %v", err)
+	}
+	if start.Type == "Kill" {
+		return fail("killed before start")
+	}
+	if start.Type != "Start" {
+		return fail("unexpected request type %q", start.Type)
+	}
+
+	// Listen on the ports the client forwards, so that they are open
+	// by the time the command starts. See forward.go.
+	fwd := newForwarder(conn, true, nil)
+	defer fwd.shutdown()
+	for _, port := range req.Listen {
+		if err := fwd.listen(port, port); err != nil {
+			return fail("%v", err)
+		}
+	}
+
+	// Watch for a Kill request (or a hangup) from the client,
+	// which may come while the command waits for its reservation,
+	// queue any standard input for the command, and pass along
+	// the traffic of forwarded connections.
+	killed := make(chan struct{})
+	input := newInputQueue(0)
+	go func() {
+		defer input.close()
+		for {
+			var req Request
+			data, err := conn.readPacket(&req)
+			if err != nil || req.Type == "Kill" {
+				close(killed)
+				return
+			}
+			switch req.Type {
+			case "Stdin":
+				if len(data) == 0 {
+					input.close()
+				} else {
+					input.add(data)
+				}
+			case "Open":
+				fwd.open(req.Chan, req.Addr)
+			case "Data":
+				fwd.data(req.Chan, data)
+			case "Close":
+				fwd.close(req.Chan, req.Error)
+			}
+		}
+	}()
+
+	// Wait for the machine: to ourselves if the client asked for that,
+	// and otherwise alongside any other ordinary commands.
+	release, waited, err := reserveMachine(req.Exclusive, killed)
+	if err != nil {
+		return fail("%v", err)
+	}
+	defer release()
+	if req.Exclusive {
+		if err := conn.writePacket(&Response{Type: "Exclusive", Waited: waited}, nil); err != nil {
+			return err
+		}
+	}
+
+	// Start the command, which the loop above has named.
+	// Only an uploaded program is for the emulated system;
+	// a command found on $PATH is this system's own, found on the
+	// PATH the command runs with.
+	c := exec.Command(name)
+	c.Args = req.Args
+	if emu != nil && uploaded {
+		c = emu.command(name, req.Args)
+	}
+	c.Dir = dir
+	if env == nil {
+		env = os.Environ()
+	}
+	extra := req.Env
+	if req.Core {
+		// Before req.Env, so that the client can choose another setting.
+		extra = slices.Concat([]string{coreEnv}, extra)
+	}
+	c.Env = slices.Concat(env, extra, pathEnv) // Concat, not append: env may be shared
+	if !uploaded && !strings.ContainsAny(name, `/\`) {
+		c.Path, c.Err = lookPathEnv(name, c.Env)
+	}
+	// The binary that may dump core, before allowCores wraps c.
+	exe := c.Path
+	if uploaded {
+		exe = name
+	}
+	if req.Core {
+		allowCores(c)
+	}
+	setpgid(c)
+	if req.Stdin {
+		stdin, err := c.StdinPipe()
+		if err != nil {
+			return fail("%v", err)
+		}
+		go input.copyTo(stdin)
+	}
+	stdout, err := c.StdoutPipe()
+	if err != nil {
+		return fail("%v", err)
+	}
+	stderr, err := c.StderrPipe()
+	if err != nil {
+		return fail("%v", err)
+	}
+	exited := make(chan struct{})
+	if err := drain.start(c, req.Drain, exited); err == errDraining {
+		return err
+	} else if err != nil {
+		return fail("%v", err)
+	}
+	started := time.Now()
+	fwd.start()
+
+	// Kill the command if the client asks.
+	// The exited check avoids killing a reused pid after the command is gone.
+	go func() {
+		select {
+		case <-killed:
+			select {
+			case <-exited:
+			default:
+				killGroup(c)
+			}
+		case <-exited:
+		}
+	}()
+
+	// Stream output until both pipes close, then report the exit status
+	// and the resources the command used.
+	var wg sync.WaitGroup
+	var output atomic.Int64
+	wg.Add(2)
+	go copyOutput(&wg, conn, c, stdout, false, &output)
+	go copyOutput(&wg, conn, c, stderr, true, &output)
+	wg.Wait()
+	c.Wait()
+	wall := time.Since(started)
+	close(exited)
+	fwd.shutdown()
+	release() // done with the machine, whenever the client reads the Exit
+	cleanCache()
+	ps := c.ProcessState
+	serverMetrics.commands.Add(1)
+	serverMetrics.commandTime.Add(int64(wall))
+	if !ps.Success() {
+		serverMetrics.commandFails.Add(1)
+	}
+	if req.Core && ps.ExitCode() < 0 {
+		// Killed by a signal: send any core dump, and the binary
+		// that dumped it. See core.go.
+		if err := serveCore(conn, dir, c.Process.Pid, started, exe); err != nil {
+			return err
+		}
+	}
+	return conn.writePacket(&Response{
+		Type:       "Exit",
+		ExitCode:   ps.ExitCode(),
+		Status:     ps.String(),
+		WallTime:   wall,
+		UserTime:   ps.UserTime(),
+		SystemTime: ps.SystemTime(),
+		MaxRSS:     maxRSS(ps),
+		Output:     output.Load(),
+	}, nil)
+}
+
+// An inputQueue holds the standard input sent by the client until the
+// command reads it. Queuing the input, rather than writing it to the
+// command as it arrives, keeps the request loop free to see a Kill
+// even when the command is not reading.
+type inputQueue struct {
+	mu     sync.Mutex
+	cond   sync.Cond
+	data   [][]byte
+	size   int // bytes in data
+	limit  int // maximum size, or 0 for no limit
+	closed bool
+}
+
+// newInputQueue returns a queue holding at most limit bytes,
+// or any number if limit is 0.
+func newInputQueue(limit int) *inputQueue {
+	q := &inputQueue{limit: limit}
+	q.cond.L = &q.mu
+	return q
+}
+
+// add queues data for the command. It reports false, queuing nothing,
+// if the data would take the queue over its limit.
+func (q *inputQueue) add(data []byte) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return true
+	}
+	if q.limit > 0 && q.size+len(data) > q.limit {
+		return false
+	}
+	q.data = append(q.data, data)
+	q.size += len(data)
+	q.cond.Signal()
+	return true
+}
+
+// close marks the end of the input. Input added later is ignored.
+func (q *inputQueue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	q.cond.Signal()
+}
+
+// copyTo writes the queued input to w, closing w at the end of the
+// input. If the command stops reading, the rest is discarded.
+func (q *inputQueue) copyTo(w io.WriteCloser) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var werr error
+	for {
+		for len(q.data) == 0 && !q.closed {
+			q.cond.Wait()
+		}
+		if len(q.data) == 0 {
+			w.Close()
+			return
+		}
+		data := q.data[0]
+		q.data = q.data[1:]
+		q.size -= len(data)
+		if werr == nil {
+			q.mu.Unlock()
+			_, werr = w.Write(data)
+			q.mu.Lock()
+		}
+	}
+}
+
+// copyOutput streams the command output read from r to the client
+// as Output responses, killing the command if the client is gone,
+// and adds the number of bytes to total.
+// It decrements wg when the output pipe closes.
+func copyOutput(wg *sync.WaitGroup, conn *Conn, c *exec.Cmd, r io.Reader, stderr bool, total *atomic.Int64) {
+	defer wg.Done()
+	buf := make([]byte, 32<<10)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			total.Add(int64(n))
+			if err := conn.writePacket(&Response{Type: "Output", Stderr: stderr}, buf[:n]); err != nil {
+				killGroup(c)
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,277 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mote
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// sshControlPath is the ControlPath setting naming the socket for the
+// shared ssh connection to a server.
+const sshControlPath = "ControlPath ~/.ssh/sockets/mote-%r@%h-%p"
+
+// sshDest returns the port arguments and the [user@]host destination
+// for the ssh://[user@]host[:port] URL u.
+func sshDest(u *url.URL) (portArgs []string, target string) {
+	if port := u.Port(); port != "" {
+		portArgs = append(portArgs, "-p", port)
+	}
+	target = u.Hostname()
+	if user := u.User.Username(); user != "" {
+		target = user + "@" + target
+	}
+	return portArgs, target
+}
+
+// sshInstallPath is where the client installs mote, with $MOTEUPGRADE,
+// on an ssh server that has none of its own or an old one.
+// It is relative to the home directory, where ssh runs commands.
+const sshInstallPath = ".cache/mote/bin/mote"
+
+// dialSSH connects to an ssh://[user@]host[:port] or ssh+hex:// server
+// by running "mote serve -" on the far end.
+//
+// If $MOTEUPGRADE is set and that fails because the server has no mote
+// command (see sshMissingError), dialSSH asks the server for its system
+// with "uname -sm" and runs the mote at sshInstallPath instead,
+// cross-compiling a current mote for the system and installing it
+// there first if it is missing or older than this one. It does the
+// same when the server's own mote is older than this one. Without
+// $MOTEUPGRADE, or over ssh+hex://, whose connections are not binary
+// safe for copying the binary, dialSSH installs nothing.
+//
+// Like the gomote transport, dialSSH needs the completed handshake to
+// know whether the server's mote ran, and which version it was, which
+// is why this transport runs the handshake itself.
+func dialSSH(u *url.URL) (*Conn, error) {
+	upgrade := os.Getenv("MOTEUPGRADE") != ""
+	hex := u.Scheme == "ssh+hex"
+	conn, err := sshConn(u, "mote")
+	if err != nil {
+		var missing *sshMissingError
+		if !upgrade || !errors.As(err, &missing) {
+			return nil, err
+		}
+		if hex {
+			return nil, fmt.Errorf("%v\nnot installing mote over ssh+hex://, which is not binary safe; install it by hand or use ssh://", err)
+		}
+		goos, goarch, unameErr := sshUname(u)
+		if unameErr != nil {
+			return nil, err
+		}
+		return sshInstalled(u, goos, goarch)
+	}
+	if conn.current() || !upgrade || hex {
+		return conn, nil
+	}
+	if conn.GOOS == "windows" {
+		// There is no portable way to install a file through the
+		// Windows command interpreter: make do with the old server.
+		return conn, nil
+	}
+	conn.Close()
+	return sshInstalled(u, conn.GOOS, conn.GOARCH)
+}
+
+// sshInstalled connects to the mote at sshInstallPath on u, first
+// installing a current mote for goos and goarch there if the one
+// there is missing or old.
+func sshInstalled(u *url.URL, goos, goarch string) (*Conn, error) {
+	if conn, err := sshConn(u, sshInstallPath); err == nil {
+		if conn.current() {
+			return conn, nil
+		}
+		conn.Close()
+	}
+	bin, err := buildMote(goos, goarch)
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(filepath.Dir(bin))
+	if err := sshInstall(u, bin); err != nil {
+		return nil, err
+	}
+	return sshConn(u, sshInstallPath)
+}
+
+// sshUname returns the GOOS and GOARCH of the server u,
+// as reported by "uname -sm".
+func sshUname(u *url.URL) (goos, goarch string, err error) {
+	out, err := exec.Command("ssh", sshArgs(u, "uname -sm")...).Output()
+	if err != nil {
+		return "", "", err
+	}
+	return unameOSArch(string(out))
+}
+
+// sshArgs returns the ssh arguments for running the command line cmd
+// on the server u, sharing one ssh connection among all the commands.
+func sshArgs(u *url.URL, cmd string) []string {
+	if home, err := os.UserHomeDir(); err == nil {
+		os.MkdirAll(filepath.Join(home, ".ssh", "sockets"), 0o700)
+	}
+	args := []string{
+		"-o", "ControlMaster auto",
+		"-o", sshControlPath,
+		"-o", "ControlPersist 1800",
+	}
+	portArgs, target := sshDest(u)
+	args = append(args, portArgs...)
+	return append(args, target, cmd)
+}
+
+// sshConn connects to the mote server started by running "mote serve -"
+// on u, using the mote binary named by mote. For an ssh+hex:// URL,
+// the server runs in hex, for connections that are not binary safe
+// (see hex.go).
+// Standard error from ssh is hidden unless an error (such as a
+// handshake timeout) happens; password prompts still work, because
+// ssh prints those directly to the terminal.
+func sshConn(u *url.URL, mote string) (*Conn, error) {
+	hex := u.Scheme == "ssh+hex"
+	serve := mote + " serve -"
+	if hex {
+		serve = mote + " serve -hex-"
+	}
+	c := exec.Command("ssh", sshArgs(u, serve)...)
+	c.Stderr = new(bytes.Buffer)
+	p, err := startProcConn(c)
+	if err != nil {
+		return nil, err
+	}
+	var rwc io.ReadWriteCloser = p
+	if hex {
+		if err := scanHexHandshake(p); err != nil {
+			return nil, sshMissing(p, p.abort(err))
+		}
+		rwc = newHexConn(p)
+	}
+	conn, err := clientConn(rwc, "")
+	if err != nil {
+		return nil, sshMissing(p, abortConn(rwc, err))
+	}
+	return conn, nil
+}
+
+// An sshMissingError is the error from running a mote command that
+// the server does not have. Its message is that of the failure.
+type sshMissingError struct {
+	err error
+}
+
+func (e *sshMissingError) Error() string { return e.err.Error() }
+func (e *sshMissingError) Unwrap() error { return e.err }
+
+// sshMissing returns err, the error from the ended ssh command p, as an
+// sshMissingError if the remote shell reported that the command was not
+// found: exit status 127, which ssh passes along, or a "not found"
+// message from a shell that exits otherwise.
+func sshMissing(p *procConn, err error) error {
+	ps := p.cmd.ProcessState
+	notFound := p.stderr != nil && strings.Contains(p.stderr.String(), "not found")
+	if ps != nil && ps.ExitCode() == 127 || notFound {
+		return &sshMissingError{err}
+	}
+	return err
+}
+
+// sshInstall copies the mote binary bin to sshInstallPath on u,
+// replacing any earlier one only once the copy is complete and
+// matches bin: in size, and in SHA-256 hash if the server has
+// sha256sum or shasum to compute it.
+func sshInstall(u *url.URL, bin string) error {
+	data, err := os.ReadFile(bin)
+	if err != nil {
+		return err
+	}
+	dir, tmp := path.Dir(sshInstallPath), sshInstallPath+".tmp"
+	script := fmt.Sprintf("mkdir -p %[1]s && cat >%[2]s && test $(wc -c <%[2]s) -eq %[3]d && "+
+		"{ h=$( (sha256sum %[2]s || shasum -a 256 %[2]s) 2>/dev/null); test -z \"$h\" || test \"${h%%%% *}\" = %[4]x; } && "+
+		"chmod 755 %[2]s && mv %[2]s %[5]s || { rm -f %[2]s; echo 'mote binary damaged in transit' >&2; exit 1; }",
+		dir, tmp, len(data), sha256.Sum256(data), sshInstallPath)
+	c := exec.Command("ssh", sshArgs(u, script)...)
+	c.Stdin = bytes.NewReader(data)
+	var out bytes.Buffer
+	c.Stdout = &out
+	c.Stderr = &out
+	if err := c.Run(); err != nil {
+		if msg := strings.TrimSpace(out.String()); msg != "" {
+			return fmt.Errorf("installing mote on %s: %v\n%s", u.Host, err, msg)
+		}
+		return fmt.Errorf("installing mote on %s: %v", u.Host, err)
+	}
+	return nil
+}
+
+// closeSSH shuts down the shared ssh connection to u, if one is running.
+func closeSSH(u *url.URL) error {
+	portArgs, target := sshDest(u)
+	closed, err := sshControlExit(sshControlPath, target, portArgs)
+	if err != nil {
+		return err
+	}
+	if closed {
+		log.Printf("closed shared ssh connection to %s", target)
+	} else {
+		log.Printf("no shared ssh connection to %s", target)
+	}
+	return nil
+}
+
+// closeSSHSocket shuts down the shared ssh connection whose control
+// socket is the file sock (~/.ssh/sockets/mote-user@host-port).
+func closeSSHSocket(sock string) error {
+	// The socket path has no % expansions, so the ssh destination
+	// argument, though required, goes unused.
+	name := strings.TrimPrefix(filepath.Base(sock), "mote-")
+	if i := strings.LastIndex(name, "-"); i > 0 {
+		name = name[:i] // drop the port
+	}
+	closed, err := sshControlExit("ControlPath "+sock, "unused", nil)
+	if err != nil {
+		return err
+	}
+	if closed {
+		log.Printf("closed shared ssh connection to %s", name)
+	} else {
+		log.Printf("no shared ssh connection to %s", name)
+	}
+	return nil
+}
+
+// sshControlExit runs ssh -O exit against the control socket named by
+// the ControlPath option, reporting whether there was a shared
+// connection to close.
+func sshControlExit(controlPath, dest string, portArgs []string) (closed bool, err error) {
+	args := append([]string{"-o", controlPath, "-O", "exit"}, portArgs...)
+	args = append(args, dest)
+	c := exec.Command("ssh", args...)
+	var out bytes.Buffer
+	c.Stdout = &out
+	c.Stderr = &out
+	if err := c.Run(); err != nil {
+		msg := strings.TrimSpace(out.String())
+		if strings.Contains(msg, "No such file or directory") || strings.Contains(msg, "Connection refused") {
+			// The control socket does not exist or is stale.
+			return false, nil
+		}
+		if msg != "" {
+			return false, fmt.Errorf("ssh -O exit: %v\n%s", err, msg)
+		}
+		return false, fmt.Errorf("ssh -O exit: %v", err)
+	}
+	return true, nil
+}
@@ -0,0 +1,28 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mote
+
+import "golang.org/x/sys/unix"
+
+// cpuModel returns the CPU model name, or "" if it is unknown.
+func cpuModel() string {
+	name, _ := unix.Sysctl("machdep.cpu.brand_string")
+	return name
+}
+
+// memTotal returns the machine's physical memory in bytes,
+// or 0 if it is unknown.
+func memTotal() int64 {
+	n, _ := unix.SysctlUint64("hw.memsize")
+	return int64(n)
+}
+
+// osVersion describes the operating system and kernel,
+// like "macOS 15.3, Darwin 24.3.0".
+func osVersion() string {
+	product, _ := unix.Sysctl("kern.osproductversion")
+	release, _ := unix.Sysctl("kern.osrelease")
+	return "macOS " + product + ", Darwin " + release
+}
@@ -0,0 +1,62 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mote
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// cpuModel returns the CPU model name, or "" if it is unknown.
+// Many arm64 kernels do not report one.
+func cpuModel() string {
+	return procField("/proc/cpuinfo", "model name")
+}
+
+// memTotal returns the machine's physical memory in bytes,
+// or 0 if it is unknown.
+func memTotal() int64 {
+	kb, _ := strconv.ParseInt(strings.TrimSuffix(procField("/proc/meminfo", "MemTotal"), " kB"), 10, 64)
+	return kb * 1024
+}
+
+// osVersion describes the operating system and kernel,
+// like "Debian GNU/Linux 12 (bookworm), Linux 6.1.0-18-amd64".
+func osVersion() string {
+	var u unix.Utsname
+	kernel := "Linux"
+	if unix.Uname(&u) == nil {
+		kernel = unix.ByteSliceToString(u.Sysname[:]) + " " + unix.ByteSliceToString(u.Release[:])
+	}
+	if name := procField("/etc/os-release", "PRETTY_NAME"); name != "" {
+		return strings.Trim(name, `"`) + ", " + kernel
+	}
+	return kernel
+}
+
+// procField returns the value of the first line in file of the form
+// "key: value" or "key=value", or "" if there is none.
+func procField(file, key string) string {
+	f, err := os.Open(file)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		k, v, ok := strings.Cut(sc.Text(), ":")
+		if !ok {
+			k, v, ok = strings.Cut(sc.Text(), "=")
+		}
+		if ok && strings.TrimSpace(k) == key {
+			return strings.TrimSpace(v)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,15 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !linux && !darwin
+
+package mote
+
+import "runtime"
+
+func cpuModel() string { return "" }
+
+func memTotal() int64 { return 0 }
+
+func osVersion() string { return runtime.GOOS }
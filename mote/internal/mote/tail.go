@@ -2,7 +2,7 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-package main
+package mote
 
 import (
 	"bufio"
@@ -28,15 +28,19 @@ const tailPort = 6683
 
 // tailNames returns the names mote has a node directory for
 // (the tail-name subdirectories of the configuration directory).
-func tailNames() []string {
+func tailNames() ([]string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return nil, err
+	}
 	var names []string
-	entries, _ := os.ReadDir(configDir())
+	entries, _ := os.ReadDir(dir)
 	for _, e := range entries {
 		if e.IsDir() && strings.HasPrefix(e.Name(), "tail-") {
 			names = append(names, strings.TrimPrefix(e.Name(), "tail-"))
 		}
 	}
-	return names
+	return names, nil
 }
 
 // loggedInTailNames returns the names this machine is logged in as.
@@ -44,25 +48,29 @@ func tailNames() []string {
 // up creates the directory before the registration that fills it in, so
 // an abandoned or failed registration leaves one behind. Such a
 // directory is not a login and must not be mistaken for one.
-func loggedInTailNames() []string {
+func loggedInTailNames() ([]string, error) {
+	all, err := tailNames()
+	if err != nil {
+		return nil, err
+	}
 	var names []string
-	for _, name := range tailNames() {
+	for _, name := range all {
 		if haveTailCredentials(name) {
 			names = append(names, name)
 		}
 	}
-	return names
+	return names, nil
 }
 
 // hostTailName returns the default name for this machine:
 // the first element of the local host name.
-func hostTailName() string {
+func hostTailName() (string, error) {
 	host, err := os.Hostname()
 	if err != nil || host == "" {
-		log.Fatalf("cannot determine host name; use mote login tail://name")
+		return "", fmt.Errorf("cannot determine host name; use mote login tail://name")
 	}
 	name, _, _ := strings.Cut(host, ".")
-	return name
+	return name, nil
 }
 
 // clientTailName returns the name to use for the local client:
@@ -74,8 +82,14 @@ func hostTailName() string {
 // or “mote serve tail://name” should not add a second node to the
 // tailnet just to run a command.
 func clientTailName() (string, error) {
-	names := loggedInTailNames()
-	host := hostTailName()
+	names, err := loggedInTailNames()
+	if err != nil {
+		return "", err
+	}
+	host, err := hostTailName()
+	if err != nil {
+		return "", err
+	}
 	if len(names) == 0 || slices.Contains(names, host) {
 		return host, nil
 	}
@@ -88,7 +102,10 @@ func clientTailName() (string, error) {
 // registeredTailName returns the single registered name,
 // for use expanding the shorthand "tail:".
 func registeredTailName() (string, error) {
-	names := loggedInTailNames()
+	names, err := loggedInTailNames()
+	if err != nil {
+		return "", err
+	}
 	switch len(names) {
 	case 0:
 		return "", fmt.Errorf("not logged in to Tailscale; run mote login tail://name or mote serve tail://name")
@@ -102,10 +119,14 @@ func registeredTailName() (string, error) {
 // the tailnet as mote-name and storing credentials in the tail-name
 // configuration subdirectory. The caller must set AuthKey to register a
 // node that has no credentials yet.
-func tsnetServer(name string) *tsnet.Server {
+func tsnetServer(name string) (*tsnet.Server, error) {
+	dir, err := tailDir(name)
+	if err != nil {
+		return nil, err
+	}
 	srv := &tsnet.Server{
 		Hostname:      "mote-" + name,
-		Dir:           tailDir(name),
+		Dir:           dir,
 		AdvertiseTags: []string{"tag:mote"}, // see the Tailscale section in doc.go
 		UserLogf:      onceLogf(log.Printf), // tsnet repeats the login URL every few seconds
 		Logf:          func(string, ...any) {},
@@ -114,7 +135,7 @@ func tsnetServer(name string) *tsnet.Server {
 		srv.Logf = log.Printf
 		srv.UserLogf = log.Printf
 	}
-	return srv
+	return srv, nil
 }
 
 // tailLogin registers the named node on the tailnet if it has no
@@ -133,7 +154,10 @@ func tailLogin(name string) error {
 	if !sc.Scan() || strings.TrimSpace(sc.Text()) == "" {
 		return fmt.Errorf("no Tailscale auth key provided")
 	}
-	srv := tsnetServer(name)
+	srv, err := tsnetServer(name)
+	if err != nil {
+		return err
+	}
 	srv.AuthKey = strings.TrimSpace(sc.Text())
 	defer srv.Close()
 	if _, err := srv.Up(context.Background()); err != nil {
@@ -218,6 +242,36 @@ func tailPeerAddr(st *ipnstate.Status, host string) (netip.Addr, bool) {
 	return netip.Addr{}, false
 }
 
+// Peers returns the names of the mote nodes on the tailnet.
+func (t *tsNet) Peers(ctx context.Context) ([]string, error) {
+	lc, err := t.srv.LocalClient()
+	if err != nil {
+		return nil, fmt.Errorf("tailscale: %v", err)
+	}
+	st, err := lc.Status(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("tailscale status: %v", err)
+	}
+	return tailMotePeers(st), nil
+}
+
+// tailMotePeers returns the sorted names of the online peers in st
+// that are mote nodes: tagged tag:mote, with a machine name mote-name.
+// Clients are mote nodes too, so not every name is a server.
+func tailMotePeers(st *ipnstate.Status) []string {
+	var names []string
+	for _, peer := range st.Peer {
+		if !peer.Online || peer.Tags == nil || !slices.Contains(peer.Tags.AsSlice(), "tag:mote") {
+			continue
+		}
+		if name, ok := strings.CutPrefix(peer.HostName, "mote-"); ok && name != "" {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return slices.Compact(names)
+}
+
 func (t *tsNet) Listen(network, addr string) (net.Listener, error) {
 	return t.srv.Listen(network, addr)
 }
@@ -2,20 +2,25 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-package main
+package mote
 
 import (
 	"net/netip"
 	"os"
+	"slices"
 	"strings"
 	"testing"
 
 	"tailscale.com/ipn/ipnstate"
 	"tailscale.com/types/key"
+	"tailscale.com/types/views"
 )
 
 func TestClientTailName(t *testing.T) {
-	host := hostTailName()
+	host, err := hostTailName()
+	if err != nil {
+		t.Fatal(err)
+	}
 	tests := []struct {
 		name    string
 		dirs    []string // node directories, "name" logged in, "name!" not
@@ -40,11 +45,19 @@ func TestClientTailName(t *testing.T) {
 				if n, ok := strings.CutSuffix(dir, "!"); ok {
 					name, loggedIn = n, false
 				}
-				if err := os.MkdirAll(tailDir(name), 0o700); err != nil {
+				dir, err := tailDir(name)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := os.MkdirAll(dir, 0o700); err != nil {
 					t.Fatal(err)
 				}
 				if loggedIn {
-					if err := os.WriteFile(tailStatePath(name), []byte("state"), 0o600); err != nil {
+					state, err := tailStatePath(name)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if err := os.WriteFile(state, []byte("state"), 0o600); err != nil {
 						t.Fatal(err)
 					}
 				}
@@ -99,3 +112,23 @@ func TestTailPeerAddr(t *testing.T) {
 		}
 	}
 }
+
+func TestTailMotePeers(t *testing.T) {
+	mote := views.SliceOf([]string{"tag:mote"})
+	other := views.SliceOf([]string{"tag:server"})
+	st := &ipnstate.Status{
+		Peer: map[key.NodePublic]*ipnstate.PeerStatus{
+			key.NewNode().Public(): {HostName: "mote-s7", Online: true, Tags: &mote},
+			key.NewNode().Public(): {HostName: "mote-a1", Online: true, Tags: &mote},
+			// Offline, untagged, and non-mote nodes are left out.
+			key.NewNode().Public(): {HostName: "mote-off", Tags: &mote},
+			key.NewNode().Public(): {HostName: "mote-untagged", Online: true},
+			key.NewNode().Public(): {HostName: "mote-db", Online: true, Tags: &other},
+			key.NewNode().Public(): {HostName: "laptop", Online: true, Tags: &mote},
+		},
+	}
+	want := []string{"a1", "s7"}
+	if got := tailMotePeers(st); !slices.Equal(got, want) {
+		t.Errorf("tailMotePeers = %q, want %q", got, want)
+	}
+}
@@ -2,7 +2,7 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-package main
+package mote
 
 import (
 	"context"
@@ -33,7 +33,7 @@ import (
 // the same machine share it, which is not possible when each command
 // brings up its own node.
 //
-// See the daemon section of protocol.md for the socket protocol.
+// See the daemon section of ../../protocol.md for the socket protocol.
 
 // daemonIdleTimeout is how long the daemon stays running with no clients.
 // It is a variable for testing.
@@ -56,23 +56,41 @@ var errLocked = errors.New("lock held by another process")
 // tailDir returns the configuration directory for the named node,
 // which holds the Tailscale credentials, the daemon's socket, lock,
 // and log file.
-func tailDir(name string) string {
-	return filepath.Join(configDir(), "tail-"+name)
+func tailDir(name string) (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "tail-"+name), nil
 }
 
-func servicePath(name string) string { return filepath.Join(tailDir(name), "service") }
-func lockPath(name string) string    { return filepath.Join(tailDir(name), "lock") }
-func logPath(name string) string     { return filepath.Join(tailDir(name), "log") }
+// tailFile returns the name of the file elem in the named node's
+// configuration directory.
+func tailFile(name, elem string) (string, error) {
+	dir, err := tailDir(name)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, elem), nil
+}
+
+func servicePath(name string) (string, error) { return tailFile(name, "service") }
+func lockPath(name string) (string, error)    { return tailFile(name, "lock") }
+func logPath(name string) (string, error)     { return tailFile(name, "log") }
 
 // tailStatePath is the file where tsnet stores the node's credentials.
 // Its presence means the node has been registered on the tailnet.
-func tailStatePath(name string) string {
-	return filepath.Join(tailDir(name), "tailscaled.state")
+func tailStatePath(name string) (string, error) {
+	return tailFile(name, "tailscaled.state")
 }
 
 // haveTailCredentials reports whether the named node has been registered.
 func haveTailCredentials(name string) bool {
-	info, err := os.Stat(tailStatePath(name))
+	file, err := tailStatePath(name)
+	if err != nil {
+		return false
+	}
+	info, err := os.Stat(file)
 	return err == nil && info.Size() > 0
 }
 
@@ -81,6 +99,7 @@ func haveTailCredentials(name string) bool {
 type tailNet interface {
 	Dial(ctx context.Context, network, addr string) (net.Conn, error)
 	Listen(network, addr string) (net.Listener, error)
+	Peers(ctx context.Context) ([]string, error)
 	Close() error
 }
 
@@ -89,7 +108,11 @@ type tailNet interface {
 // daemonConn returns a connection to the daemon for the named local node,
 // starting the daemon if it is not already running.
 func daemonConn(name string) (net.Conn, error) {
-	if c, err := net.Dial("unix", servicePath(name)); err == nil {
+	path, err := servicePath(name)
+	if err != nil {
+		return nil, err
+	}
+	if c, err := net.Dial("unix", path); err == nil {
 		return c, nil
 	}
 	if err := startDaemon(name); err != nil {
@@ -97,7 +120,7 @@ func daemonConn(name string) (net.Conn, error) {
 	}
 	deadline := time.Now().Add(daemonStartTimeout)
 	for {
-		c, err := net.Dial("unix", servicePath(name))
+		c, err := net.Dial("unix", path)
 		if err == nil {
 			return c, nil
 		}
@@ -115,14 +138,22 @@ func startDaemon(name string) error {
 	if err := tailLogin(name); err != nil {
 		return err
 	}
-	exe, err := os.Executable()
+	exe, err := moteExecutable()
 	if err != nil {
 		return err
 	}
-	if err := os.MkdirAll(tailDir(name), 0o700); err != nil {
+	dir, err := tailDir(name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	logFile, err := logPath(name)
+	if err != nil {
 		return err
 	}
-	f, err := os.OpenFile(logPath(name), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	f, err := os.OpenFile(logFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
 	if err != nil {
 		return err
 	}
@@ -135,7 +166,7 @@ func startDaemon(name string) error {
 		args = append([]string{"-v"}, args...)
 	}
 	c := exec.Command(exe, args...)
-	c.Dir = configDir() // do not hold the caller's directory open
+	c.Dir = dir // do not hold the caller's directory open
 	c.Stdout = f
 	c.Stderr = f
 	detach(c)
@@ -146,11 +177,28 @@ func startDaemon(name string) error {
 	return c.Process.Release()
 }
 
+// moteExecutable returns the mote command to run as the daemon:
+// this program, if it is mote, and otherwise the mote on $PATH.
+func moteExecutable() (string, error) {
+	if isMote {
+		return os.Executable()
+	}
+	exe, err := exec.LookPath("mote")
+	if err != nil {
+		return "", fmt.Errorf("tail:// needs the mote command installed: %v", err)
+	}
+	return exe, nil
+}
+
 // daemonLogTail returns the end of the daemon's log file, formatted for
 // appending to an error message. Whatever stopped the daemon from
 // starting, such as an expired auth key, is at the end of that file.
 func daemonLogTail(name string) string {
-	data, err := os.ReadFile(logPath(name))
+	file, err := logPath(name)
+	if err != nil {
+		return ""
+	}
+	data, err := os.ReadFile(file)
 	if err != nil {
 		return ""
 	}
@@ -202,10 +250,39 @@ func daemonDial(name, addr string) (io.ReadWriteCloser, error) {
 	}
 }
 
+// daemonPeers returns the names of the mote nodes on the tailnet,
+// as seen by the daemon for the named local node.
+func daemonPeers(name string) ([]string, error) {
+	conn, err := daemonConn(name)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	c := newConn(conn)
+	if err := c.writePacket(&Request{Type: "Peers"}, nil); err != nil {
+		return nil, fmt.Errorf("listing tailnet peers: %v", err)
+	}
+	var resp Response
+	if _, err := c.readPacket(&resp); err != nil {
+		return nil, fmt.Errorf("listing tailnet peers: %v", err)
+	}
+	if resp.Error != "" {
+		return nil, errors.New(resp.Error)
+	}
+	if resp.Type != "Peers" {
+		return nil, fmt.Errorf("listing tailnet peers: unexpected response type %q", resp.Type)
+	}
+	return resp.Peers, nil
+}
+
 // daemonStop shuts down the daemon for the named local node,
 // if one is running. It does not start a daemon to stop it.
 func daemonStop(name string) error {
-	conn, err := net.Dial("unix", servicePath(name))
+	path, err := servicePath(name)
+	if err != nil {
+		return err
+	}
+	conn, err := net.Dial("unix", path)
 	if err != nil {
 		log.Printf("tailscale daemon for mote-%s not running", name)
 		return nil
@@ -239,7 +316,7 @@ func daemonServe(name string) error {
 	}
 	defer conn.Close()
 	c := newConn(conn)
-	if err := c.writePacket(&Request{Type: "Serve", Env: os.Environ()}, nil); err != nil {
+	if err := c.writePacket(&Request{Type: "Serve", Env: os.Environ(), Addr: *metricsAddr}, nil); err != nil {
 		return err
 	}
 	for {
@@ -297,14 +374,22 @@ func cmdTailDaemon(args []string) {
 // tests pass a stand-in network instead.
 func runDaemon(name string, tn tailNet) error {
 	own := tn == nil
-	if err := os.MkdirAll(tailDir(name), 0o700); err != nil {
+	dir, err := tailDir(name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
 		return err
 	}
 
 	// The lock names the one running daemon: two tsnet servers sharing a
 	// state directory would corrupt it. The kernel drops the lock if the
 	// daemon dies, so there is no stale lock to clean up.
-	lock, err := lockFile(lockPath(name))
+	lockName, err := lockPath(name)
+	if err != nil {
+		return err
+	}
+	lock, err := lockFile(lockName)
 	if err == errLocked {
 		return nil // another daemon is already running
 	}
@@ -319,7 +404,10 @@ func runDaemon(name string, tn tailNet) error {
 		if !haveTailCredentials(name) {
 			return fmt.Errorf("no Tailscale credentials for mote-%s; run mote login tail://%s", name, name)
 		}
-		srv := tsnetServer(name)
+		srv, err := tsnetServer(name)
+		if err != nil {
+			return err
+		}
 		if _, err := srv.Up(context.Background()); err != nil {
 			srv.Close()
 			return fmt.Errorf("tailscale: %v", err)
@@ -337,7 +425,7 @@ func runDaemon(name string, tn tailNet) error {
 	}
 	if own {
 		log.SetOutput(d.log)
-		log.Printf("serving %s for mote-%s", servicePath(name), name)
+		log.Printf("serving %s for mote-%s", d.svc.Addr(), name)
 	}
 	return d.run()
 }
@@ -381,11 +469,17 @@ func (d *daemon) stop() { d.svc.Close() }
 // socket left behind by a daemon that died. Removing it is safe because
 // the caller holds the daemon lock.
 func listenService(name string) (net.Listener, error) {
-	dir := tailDir(name)
+	dir, err := tailDir(name)
+	if err != nil {
+		return nil, err
+	}
 	if err := os.MkdirAll(dir, 0o700); err != nil {
 		return nil, err
 	}
-	path := servicePath(name)
+	path, err := servicePath(name)
+	if err != nil {
+		return nil, err
+	}
 	if c, err := net.Dial("unix", path); err == nil {
 		// Should not happen while holding the lock, but never take a
 		// socket away from a daemon that is answering on it.
@@ -437,6 +531,9 @@ func (d *daemon) client(conn net.Conn) {
 		d.dial(c, conn, &req)
 	case "Serve":
 		d.serve(c, conn, &req)
+	case "Peers":
+		d.peers(c)
+		conn.Close()
 	case "Stop":
 		log.Printf("stopped by mote close")
 		c.writePacket(&Response{Type: "Stopping"}, nil)
@@ -483,6 +580,18 @@ func (d *daemon) dial(c *Conn, conn net.Conn, req *Request) {
 	proxy(conn, nc)
 }
 
+// peers answers a Peers request with the mote nodes on the tailnet.
+func (d *daemon) peers(c *Conn) {
+	ctx, cancel := context.WithTimeout(context.Background(), daemonDialTimeout)
+	defer cancel()
+	names, err := d.net.Peers(ctx)
+	if err != nil {
+		c.writePacket(&Response{Type: "Error", Error: err.Error()}, nil)
+		return
+	}
+	c.writePacket(&Response{Type: "Peers", Peers: names}, nil)
+}
+
 // serve registers a mote server, starts listening on the tailnet, and
 // waits for the mote server to hang up, which stops the listener.
 func (d *daemon) serve(c *Conn, conn net.Conn, req *Request) {
@@ -499,6 +608,16 @@ func (d *daemon) serve(c *Conn, conn net.Conn, req *Request) {
 		c.writePacket(&Response{Type: "Error", Error: err.Error()}, nil)
 		return
 	}
+	var metrics net.Listener
+	if req.Addr != "" {
+		metrics, err = serveMetrics(req.Addr)
+		if err != nil {
+			d.mu.Unlock()
+			ln.Close()
+			c.writePacket(&Response{Type: "Error", Error: err.Error()}, nil)
+			return
+		}
+	}
 	d.serving = true
 	d.mu.Unlock()
 
@@ -507,6 +626,9 @@ func (d *daemon) serve(c *Conn, conn net.Conn, req *Request) {
 		d.serving = false
 		d.mu.Unlock()
 		ln.Close()
+		if metrics != nil {
+			metrics.Close()
+		}
 		d.log.detach(c)
 	}()
 
@@ -516,7 +638,8 @@ func (d *daemon) serve(c *Conn, conn net.Conn, req *Request) {
 	// Send the daemon's log output to this mote server's terminal,
 	// so that Tailscale's messages and session errors are visible.
 	d.log.attach(c)
-	go serveListener(ln, "", req.Env)
+	// The daemon is stopped through the mote server, not by signals.
+	go serveSessions(ln, "", req.Env)
 
 	// Sessions run until they finish; the mote server hanging up only
 	// stops the listener. Read until then. The client sends nothing.
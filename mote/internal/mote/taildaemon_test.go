@@ -2,7 +2,7 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-package main
+package mote
 
 import (
 	"bytes"
@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -19,7 +20,8 @@ import (
 // local TCP listener, and Dial connects to it, so that a daemon, a mote
 // server, and a mote client can all run in one process.
 type fakeNet struct {
-	ln net.Listener
+	ln    net.Listener
+	peers []string
 }
 
 func (f *fakeNet) Listen(network, addr string) (net.Listener, error) {
@@ -38,6 +40,10 @@ func (f *fakeNet) Dial(ctx context.Context, network, addr string) (net.Conn, err
 	return net.Dial("tcp", f.ln.Addr().String())
 }
 
+func (f *fakeNet) Peers(ctx context.Context) ([]string, error) {
+	return f.peers, nil
+}
+
 func (f *fakeNet) Close() error {
 	if f.ln != nil {
 		return f.ln.Close()
@@ -59,6 +65,16 @@ func setupDaemonDirs(t *testing.T) {
 	t.Setenv("MOTECONFIG", dir)
 }
 
+// testServicePath returns the daemon socket for the node "test".
+func testServicePath(t *testing.T) string {
+	t.Helper()
+	path, err := servicePath("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
 // startTestDaemon runs a daemon for the node "test" on fn,
 // stopping it when the test ends.
 func startTestDaemon(t *testing.T, fn *fakeNet) string {
@@ -101,7 +117,7 @@ func TestDaemonDial(t *testing.T) {
 	}
 	defer conn.Close()
 	var stdout, stderr bytes.Buffer
-	w, err := conn.Run(&Exec{
+	w, err := conn.Run(t.Context(), &Exec{
 		Args:   []string{"echo", "through the daemon"},
 		Dir:    "/mote-test",
 		Stdout: &stdout,
@@ -143,6 +159,19 @@ func TestDaemonStop(t *testing.T) {
 	}
 }
 
+func TestDaemonPeers(t *testing.T) {
+	setupDaemonDirs(t)
+	fn := &fakeNet{peers: []string{"s1", "s2"}}
+	name := startTestDaemon(t, fn)
+	peers, err := daemonPeers(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(peers, fn.peers) {
+		t.Errorf("daemonPeers = %q, want %q", peers, fn.peers)
+	}
+}
+
 func TestDaemonDialNoServer(t *testing.T) {
 	setupDaemonDirs(t)
 	fn := new(fakeNet)
@@ -221,7 +250,7 @@ func TestDaemonServe(t *testing.T) {
 	}
 	defer remote.Close()
 	var stdout, stderr bytes.Buffer
-	w, err := remote.Run(&Exec{
+	w, err := remote.Run(t.Context(), &Exec{
 		Args:   []string{"sh", "-c", "echo $MOTE_TEST_ENV"},
 		Dir:    "/mote-test",
 		Stdout: &stdout,
@@ -287,7 +316,7 @@ func TestDaemonIdle(t *testing.T) {
 	go func() { done <- d.run() }()
 
 	// A connected client keeps the daemon alive past the timeout.
-	hold, err := net.Dial("unix", servicePath("test"))
+	hold, err := net.Dial("unix", testServicePath(t))
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -306,7 +335,7 @@ func TestDaemonIdle(t *testing.T) {
 		d.stop()
 		t.Fatal("daemon did not exit when idle")
 	}
-	if c, err := net.Dial("unix", servicePath("test")); err == nil {
+	if c, err := net.Dial("unix", testServicePath(t)); err == nil {
 		c.Close()
 		t.Error("daemon socket still answering after exit")
 	}
@@ -327,7 +356,7 @@ func TestDaemonLock(t *testing.T) {
 	var hold net.Conn
 	for i := 0; ; i++ {
 		var err error
-		if hold, err = net.Dial("unix", servicePath("test")); err == nil {
+		if hold, err = net.Dial("unix", testServicePath(t)); err == nil {
 			break
 		}
 		select {
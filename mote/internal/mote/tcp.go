@@ -2,7 +2,7 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-package main
+package mote
 
 import (
 	"fmt"
@@ -77,5 +77,12 @@ func serveTCP(rawURL string) {
 	}
 	port := ln.Addr().(*net.TCPAddr).Port
 	log.Printf("serving tcp://%s", net.JoinHostPort(host, fmt.Sprint(port)))
-	log.Fatal(serveListener(ln, password, nil))
+	if *metricsAddr != "" {
+		if _, err := serveMetrics(*metricsAddr); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if err := serveListener(ln, password, nil); err != nil {
+		log.Fatal(err)
+	}
 }
@@ -2,7 +2,7 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-package main
+package mote
 
 import (
 	"fmt"
@@ -102,14 +102,18 @@ func TestTCPPassword(t *testing.T) {
 	}
 
 	// Comments and blank lines are ignored; a line with no password is not.
-	if err := os.WriteFile(passwordFile(), []byte("# comment\n\ntcp://h:1\ts3cret\n"), 0o600); err != nil {
+	file, err := passwordFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, []byte("# comment\n\ntcp://h:1\ts3cret\n"), 0o600); err != nil {
 		t.Fatal(err)
 	}
 	if pw, err := lookupPassword("tcp://h:1"); err != nil || pw != "s3cret" {
 		t.Errorf("lookupPassword = %q, %v; want %q", pw, err, "s3cret")
 	}
 	for _, bad := range []string{"tcp://h:1\n", "tcp://h:1 \n"} {
-		if err := os.WriteFile(passwordFile(), []byte(bad), 0o600); err != nil {
+		if err := os.WriteFile(file, []byte(bad), 0o600); err != nil {
 			t.Fatal(err)
 		}
 		if _, err := readPasswords(); err == nil {
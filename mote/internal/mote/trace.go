@@ -0,0 +1,383 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mote
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Session traces.
+//
+// Setting $MOTETRACE to a file name makes every Conn in the process,
+// client or server, append a record of each packet it sends or receives
+// to that file, one JSON object per line: when, which connection, which
+// direction, the packet's JSON section, and the length of its binary
+// section. The data itself, which can hold program input and output,
+// is recorded only if $MOTETRACEDATA is also set to 1, and then only
+// for packets sent with JSON, other than uploads, so that the requests
+// of a client session can be replayed exactly: handshake packets hold
+// key exchange messages, and uploads can be rebuilt from the files they
+// came from. "mote trace" prints a trace or replays one of its client
+// sessions against a server.
+
+// A traceRecord is one line of a trace file.
+type traceRecord struct {
+	Time time.Time
+	Pid  int             // process that recorded the packet
+	Conn int             // connection, numbered from 1 within the process
+	Dir  string          // "send" or "recv"
+	JSON json.RawMessage `json:",omitzero"` // the packet's JSON section
+	Size int64           // length of the packet's binary section
+	Data []byte          `json:",omitzero"` // the binary section, if recorded
+}
+
+// maxTraceData is the most data recorded for one packet. Stdin and
+// forwarded Data packets, which replay needs, are smaller than this.
+const maxTraceData = 64 << 10
+
+// A tracer writes packet records to the $MOTETRACE file.
+type tracer struct {
+	mu    sync.Mutex
+	w     io.Writer
+	data  bool         // record packet data ($MOTETRACEDATA=1)
+	conns atomic.Int64 // connections numbered so far
+}
+
+var (
+	traceOnce sync.Once
+	theTracer *tracer
+)
+
+// getTracer returns the tracer for $MOTETRACE, or nil if it is not set.
+// A trace file that cannot be opened is reported once and ignored.
+func getTracer() *tracer {
+	traceOnce.Do(func() {
+		file := os.Getenv("MOTETRACE")
+		if file == "" {
+			return
+		}
+		f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o666)
+		if err != nil {
+			log.Printf("ignoring $MOTETRACE: %v", err)
+			return
+		}
+		theTracer = &tracer{w: f, data: os.Getenv("MOTETRACEDATA") == "1"}
+	})
+	return theTracer
+}
+
+// A connTrace records the packets of one Conn.
+type connTrace struct {
+	t  *tracer
+	id int
+}
+
+// newConnTrace returns the connTrace for a new Conn,
+// or nil if $MOTETRACE is not set.
+func newConnTrace() *connTrace {
+	t := getTracer()
+	if t == nil {
+		return nil
+	}
+	return &connTrace{t: t, id: int(t.conns.Add(1))}
+}
+
+// record records a packet sent or received, with JSON section enc,
+// a binary section of the given size, and data, the binary section
+// itself if the caller has it, kept only if the tracer records data.
+// A nil connTrace records nothing.
+func (ct *connTrace) record(dir string, enc []byte, size int64, data []byte) {
+	if ct == nil {
+		return
+	}
+	r := &traceRecord{
+		Time: time.Now(),
+		Pid:  os.Getpid(),
+		Conn: ct.id,
+		Dir:  dir,
+		JSON: enc,
+		Size: size,
+	}
+	if ct.t.data && dir == "send" && len(enc) > 0 && packetType(enc) != "Upload" {
+		r.Data = data[:min(len(data), maxTraceData)]
+	}
+	js, err := json.Marshal(r)
+	if err != nil {
+		return
+	}
+	ct.t.mu.Lock()
+	defer ct.t.mu.Unlock()
+	ct.t.w.Write(append(js, '\n'))
+}
+
+// packetType returns the Type in a packet's JSON section.
+func packetType(enc []byte) string {
+	var p struct{ Type string }
+	json.Unmarshal(enc, &p)
+	return p.Type
+}
+
+// readTrace reads the records in the trace file.
+func readTrace(file string) ([]*traceRecord, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var list []*traceRecord
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 2*maxTraceData+maxJSON)
+	for n := 1; sc.Scan(); n++ {
+		r := new(traceRecord)
+		if err := json.Unmarshal(sc.Bytes(), r); err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", file, n, err)
+		}
+		list = append(list, r)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	return list, nil
+}
+
+// connName returns the name "mote trace" uses for r's connection.
+func (r *traceRecord) connName() string {
+	return fmt.Sprintf("%d.%d", r.Pid, r.Conn)
+}
+
+// formatRecord formats r for printing, with its time relative to start.
+func formatRecord(r *traceRecord, start time.Time) string {
+	arrow := "->"
+	if r.Dir == "recv" {
+		arrow = "<-"
+	}
+	s := fmt.Sprintf("%10.6f %s %s", r.Time.Sub(start).Seconds(), r.connName(), arrow)
+	if len(r.JSON) > 0 {
+		s += " " + string(r.JSON)
+	} else {
+		s += " (no JSON)"
+	}
+	if r.Size > 0 {
+		s += fmt.Sprintf(" +%d bytes", r.Size)
+	}
+	return s
+}
+
+// cmdTrace implements "mote trace [-conn pid.n] file" and
+// "mote trace -replay [-conn pid.n] [@name] file".
+func cmdTrace(args []string) {
+	flags := flag.NewFlagSet("trace", flag.ExitOnError)
+	flags.Usage = usage
+	replay := flags.Bool("replay", false, "replay a client session against a server")
+	connFlag := flags.String("conn", "", "only the connection `pid.n` (default all, or for -replay the first client session)")
+	flags.Parse(args)
+	server := ""
+	if *replay && flags.NArg() == 2 && strings.HasPrefix(flags.Arg(0), "@") {
+		server = flags.Arg(0)[1:]
+	} else if flags.NArg() != 1 {
+		usage()
+	}
+	list, err := readTrace(flags.Arg(flags.NArg() - 1))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *replay {
+		session := clientSession(list, *connFlag)
+		if session == nil {
+			log.Fatalf("no client session in trace")
+		}
+		if err := replayTrace(server, session, os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if len(list) == 0 {
+		return
+	}
+	for _, r := range list {
+		if *connFlag == "" || r.connName() == *connFlag {
+			fmt.Println(formatRecord(r, list[0].Time))
+		}
+	}
+}
+
+// clientSession returns the records of the named connection in list,
+// or, if name is empty, those of the first client session: the first
+// connection whose first packet with JSON is one it receives, the
+// server's Info. (A server's first such packet is the Info it sends.)
+func clientSession(list []*traceRecord, name string) []*traceRecord {
+	if name == "" {
+		seen := make(map[string]bool)
+		for _, r := range list {
+			if len(r.JSON) == 0 || seen[r.connName()] {
+				continue
+			}
+			seen[r.connName()] = true
+			if r.Dir == "recv" && packetType(r.JSON) == "Info" {
+				name = r.connName()
+				break
+			}
+		}
+	}
+	var session []*traceRecord
+	for _, r := range list {
+		if r.connName() == name {
+			session = append(session, r)
+		}
+	}
+	return session
+}
+
+// replayTrace connects to server and sends it the requests of the
+// recorded client session, printing each packet to w as it is sent or
+// received, in the format of a trace. Each request waits until the
+// server has sent as many responses as it had in the recording, so
+// that, say, Start follows Ready; a server that never sends them
+// leaves replay waiting, as it would have the client. An Upload is
+// rebuilt from the files the session's Setup named, sending what the
+// new server needs, and data the trace lacks is sent as zero bytes.
+func replayTrace(server string, session []*traceRecord, w io.Writer) error {
+	// Find the requests, and how many responses preceded each,
+	// not counting those before the first request: the Info read
+	// in connecting. Handshake packets, with no JSON, are left out;
+	// connecting makes its own.
+	var sends []*traceRecord
+	var waits []int
+	files := make(map[string]*File)
+	base, n := 0, 0
+	for _, r := range session {
+		if len(r.JSON) == 0 {
+			continue
+		}
+		if r.Dir == "recv" {
+			n++
+			continue
+		}
+		if len(sends) == 0 {
+			base = n
+		}
+		var req Request
+		json.Unmarshal(r.JSON, &req)
+		for _, f := range req.Files {
+			files[f.Hash] = f
+		}
+		sends = append(sends, r)
+		waits = append(waits, n-base)
+	}
+
+	aliases, err := resolveAliases(server, "")
+	if err != nil {
+		return err
+	}
+	conn, _, _, err := dialAliases(aliases)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	start := time.Now()
+	var mu sync.Mutex
+	show := func(dir string, enc []byte, size int64) {
+		mu.Lock()
+		defer mu.Unlock()
+		r := &traceRecord{Time: time.Now(), Pid: os.Getpid(), Conn: 1, Dir: dir, JSON: enc, Size: size}
+		fmt.Fprintln(w, formatRecord(r, start))
+	}
+
+	// Read and print the responses, passing them along.
+	resps := make(chan *Response, 1024)
+	go func() {
+		defer close(resps)
+		for {
+			var raw json.RawMessage
+			size, body, err := conn.readPacketStream(&raw)
+			if err == nil {
+				_, err = io.Copy(io.Discard, body)
+			}
+			if err != nil {
+				return
+			}
+			show("recv", raw, size)
+			resp := new(Response)
+			json.Unmarshal(raw, resp)
+			resps <- resp
+		}
+	}()
+	received := 0
+	exited := false // the server sent Exit, its last word
+	var need []string
+	// next waits for the next response, reporting false at the end of
+	// the session.
+	next := func() bool {
+		resp, ok := <-resps
+		if !ok {
+			return false
+		}
+		received++
+		switch resp.Type {
+		case "Need":
+			need = resp.Need
+		case "Exit":
+			exited = true
+		}
+		return true
+	}
+
+	for i, r := range sends {
+		for received < waits[i] && !exited {
+			if !next() {
+				break
+			}
+		}
+		if received < waits[i] || exited {
+			return fmt.Errorf("server ended session after %d of %d requests", i, len(sends))
+		}
+		if packetType(r.JSON) == "Upload" {
+			if len(need) == 0 {
+				continue // the server had everything cached
+			}
+			var readers []io.Reader
+			var size int64
+			for _, hash := range need {
+				f := files[hash]
+				if f == nil {
+					return fmt.Errorf("server needs unknown hash %s", hash)
+				}
+				readers = append(readers, io.LimitReader(&lazyFile{name: filepath.FromSlash(f.Path)}, f.Size))
+				size += f.Size
+			}
+			show("send", r.JSON, size)
+			if err := conn.writePacketStream(json.RawMessage(r.JSON), size, io.MultiReader(readers...)); err != nil {
+				return err
+			}
+			continue
+		}
+		data := r.Data
+		if int64(len(data)) < r.Size {
+			// Not recorded, or not in full: send the right amount anyway.
+			data = append(data, make([]byte, r.Size-int64(len(data)))...)
+		}
+		show("send", r.JSON, int64(len(data)))
+		if err := conn.writePacket(json.RawMessage(r.JSON), data); err != nil {
+			return err
+		}
+	}
+
+	// Wait for the server to end the session.
+	for next() {
+	}
+	return nil
+}
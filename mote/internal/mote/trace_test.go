@@ -0,0 +1,106 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mote
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// traceSession runs cat with standard input "input" on a local server,
+// recording the client's packets, and their data if data is set,
+// and returns the trace file.
+func traceSession(t *testing.T, data bool) string {
+	t.Helper()
+	var buf bytes.Buffer
+	conn := startServeClient(t, "")
+	// The Info that startServeClient read is missing from the trace,
+	// so record a stand-in for it, as clientSession looks for one.
+	conn.trace = &connTrace{t: &tracer{w: &buf, data: data}, id: 1}
+	conn.trace.record("recv", []byte(`{"Type":"Info"}`), 0, nil)
+	var outb bytes.Buffer
+	w, err := conn.Run(t.Context(), &Exec{
+		Args:   []string{"cat"},
+		Dir:    "/mote-test",
+		Stdin:  strings.NewReader("input"),
+		Stdout: &outb,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != 0 || outb.String() != "input" {
+		t.Fatalf("code=%d stdout=%q, want 0, %q", w.Code, outb.String(), "input")
+	}
+	file := filepath.Join(t.TempDir(), "trace")
+	if err := os.WriteFile(file, buf.Bytes(), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestTrace(t *testing.T) {
+	setupDirs(t)
+	list, err := readTrace(traceSession(t, false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var packets []string
+	for _, r := range list {
+		packets = append(packets, r.Dir+" "+packetType(r.JSON))
+		if r.Data != nil {
+			t.Errorf("recorded data %q without $MOTETRACEDATA", r.Data)
+		}
+	}
+	// Output and the end of the input can come in either order.
+	want := []string{"recv Info", "send Setup", "recv Ready", "send Start", "send Stdin", "send Stdin", "recv Output", "recv Exit"}
+	if !slices.Equal(slices.Sorted(slices.Values(packets)), slices.Sorted(slices.Values(want))) || packets[len(packets)-1] != "recv Exit" {
+		t.Errorf("packets:\n\t%s\nwant:\n\t%s", strings.Join(packets, ", "), strings.Join(want, ", "))
+	}
+	if s := clientSession(list, ""); len(s) != len(list) {
+		t.Errorf("clientSession found %d records, want %d", len(s), len(list))
+	}
+}
+
+func TestTraceData(t *testing.T) {
+	setupDirs(t)
+	list, err := readTrace(traceSession(t, true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdin := ""
+	for _, r := range list {
+		if r.Dir == "send" && packetType(r.JSON) == "Stdin" {
+			stdin += string(r.Data)
+		} else if r.Data != nil {
+			t.Errorf("recorded data for %s %s", r.Dir, r.JSON)
+		}
+	}
+	if stdin != "input" {
+		t.Errorf("recorded Stdin data %q, want %q", stdin, "input")
+	}
+}
+
+func TestTraceReplay(t *testing.T) {
+	for _, data := range []bool{false, true} {
+		setupDirs(t)
+		list, err := readTrace(traceSession(t, data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out bytes.Buffer
+		if err := replayTrace("local://", clientSession(list, ""), &out); err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range []string{`-> {"Type":"Setup"`, `-> {"Type":"Stdin"} +5 bytes`, `<- {"Type":"Output"} +5 bytes`, `<- {"Type":"Exit"`} {
+			if !strings.Contains(out.String(), want) {
+				t.Errorf("data=%v: replay output missing %s:\n%s", data, want, out.String())
+			}
+		}
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mote
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+)
+
+// The unix transport reaches a server on the same machine through a
+// Unix domain socket, named by a unix:///path/to/socket URL.
+//
+// Like the ssh transport, and unlike tcp://, it needs no password: the
+// server makes the socket file readable and writable only by its own
+// user, and, where the system reports who is at the other end of a
+// connection (see peerUID), it also hangs up on connections from other
+// users, in case the socket's directory lets them in another way.
+
+// unixPath returns the socket path in the unix:///path URL u.
+func unixPath(u *url.URL) (string, error) {
+	if u.Host != "" || u.Path == "" || u.RawQuery != "" {
+		return "", fmt.Errorf("unix server URL must have the form unix:///path/to/socket")
+	}
+	return u.Path, nil
+}
+
+// dialUnix connects to a unix:///path server.
+func dialUnix(u *url.URL) (io.ReadWriteCloser, error) {
+	path, err := unixPath(u)
+	if err != nil {
+		return nil, err
+	}
+	return net.Dial("unix", path)
+}
+
+// serveUnix implements "mote serve unix:///path".
+func serveUnix(rawURL string) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		log.Fatal(err)
+	}
+	path, err := unixPath(u)
+	if err != nil {
+		log.Fatal(err)
+	}
+	ln, err := listenUnix(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("serving unix://%s", path)
+	if *metricsAddr != "" {
+		if _, err := serveMetrics(*metricsAddr); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if err := serveListener(ln, "", nil); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// listenUnix listens on the socket path for connections from this
+// user, first removing a socket left behind by a server that died.
+func listenUnix(path string) (net.Listener, error) {
+	if c, err := net.Dial("unix", path); err == nil {
+		// Never take a socket away from a server answering on it.
+		c.Close()
+		return nil, fmt.Errorf("%s: a server is already running there", path)
+	}
+	if info, err := os.Lstat(path); err == nil && info.Mode().Type() == os.ModeSocket {
+		os.Remove(path)
+	}
+	if runtime.GOOS == "windows" {
+		// Access to the socket follows the directory's ACL, not its mode.
+		ln, err := net.Listen("unix", path)
+		if err != nil {
+			return nil, err
+		}
+		return &userListener{Listener: ln}, nil
+	}
+
+	// Create the socket in a new directory that only this user can
+	// enter, make it private, and only then move it into place, so that
+	// there is no moment when another user can connect to it.
+	dir, err := os.MkdirTemp(filepath.Dir(path), ".mote")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	tmp := filepath.Join(dir, "s")
+	ln, err := net.Listen("unix", tmp)
+	if err != nil {
+		return nil, err
+	}
+	// The socket is removed at path, not tmp; see userListener.Close.
+	ln.(*net.UnixListener).SetUnlinkOnClose(false)
+	if err := os.Chmod(tmp, 0o600); err != nil {
+		ln.Close()
+		return nil, err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		ln.Close()
+		return nil, err
+	}
+	return &userListener{Listener: ln, path: path}, nil
+}
+
+// A userListener is a listener that accepts only connections from
+// processes running as this user, where the system can tell.
+type userListener struct {
+	net.Listener
+	path   string // socket to remove on Close, if not removed by the listener
+	remove sync.Once
+}
+
+func (l *userListener) Close() error {
+	err := l.Listener.Close()
+	if l.path != "" {
+		l.remove.Do(func() { os.Remove(l.path) })
+	}
+	return err
+}
+
+func (l *userListener) Accept() (net.Conn, error) {
+	for {
+		c, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		uid, err := peerUID(c)
+		if errors.Is(err, errUnsupported) || err == nil && uid == os.Getuid() {
+			return c, nil
+		}
+		if err != nil {
+			log.Printf("unix: checking peer: %v", err)
+		} else {
+			log.Printf("unix: rejected connection from uid %d", uid)
+		}
+		c.Close()
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mote
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestUnixTransport(t *testing.T) {
+	setupDirs(t)
+	path := filepath.Join(t.TempDir(), "mote.sock")
+	ln, err := listenUnix(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go serveListener(ln, "", nil)
+
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if perm := info.Mode().Perm(); perm != 0o600 {
+			t.Errorf("socket mode %v, want 0600", perm)
+		}
+	}
+	if _, err := listenUnix(path); err == nil {
+		t.Errorf("second listenUnix succeeded while a server is running")
+	}
+
+	conn, err := dialServer("unix://" + filepath.ToSlash(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	runConn(t, conn, []string{"echo", "over unix"}, "over unix\n")
+
+	if _, err := dialServer("unix://host/sock"); err == nil {
+		t.Errorf("dialServer(unix://host/sock) succeeded")
+	}
+
+	// Closing the listener removes the socket, leaving nothing behind.
+	ln.Close()
+	if entries, err := os.ReadDir(filepath.Dir(path)); err != nil || len(entries) != 0 {
+		t.Errorf("after Close, directory holds %v, %v; want nothing", entries, err)
+	}
+}
+
+func TestUnixStaleSocket(t *testing.T) {
+	// A socket left behind by a server that died is replaced.
+	path := filepath.Join(t.TempDir(), "mote.sock")
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln.(*net.UnixListener).SetUnlinkOnClose(false)
+	ln.Close()
+	ln, err = listenUnix(path)
+	if err != nil {
+		t.Fatalf("listenUnix over stale socket: %v", err)
+	}
+	ln.Close()
+}
+
+func TestPeerUID(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("peer credentials only on linux")
+	}
+	path := filepath.Join(t.TempDir(), "s")
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	c, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	s, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if uid, err := peerUID(s); err != nil || uid != os.Getuid() {
+		t.Errorf("peerUID = %d, %v, want %d", uid, err, os.Getuid())
+	}
+}
+
+func TestLocalTransport(t *testing.T) {
+	setupDirs(t)
+	conn, err := dialServer("local://")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if conn.GOOS != runtime.GOOS || conn.GOARCH != runtime.GOARCH {
+		t.Errorf("server is %s-%s, want %s-%s", conn.GOOS, conn.GOARCH, runtime.GOOS, runtime.GOARCH)
+	}
+	runConn(t, conn, []string{"echo", "in process"}, "in process\n")
+}
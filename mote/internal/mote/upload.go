@@ -2,7 +2,7 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-package main
+package mote
 
 import (
 	"crypto/sha256"
@@ -10,6 +10,7 @@ import (
 	"io"
 	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 )
@@ -18,28 +19,42 @@ import (
 // if it names a file (contains a slash), the -u paths, and, if testdata
 // is true, the testdata directories from the current directory up to
 // the Go module root.
-func uploadList(cmdName string, extra []string, testdata bool) ([]*File, error) {
+//
+// Files and directories inside the -u and testdata directories are
+// left out if they match any of the exclude patterns (see excluded).
+func uploadList(cmdName string, extra []string, testdata bool, exclude []string) ([]*File, error) {
 	var files []*File
+	err := walkUploads(cmdName, extra, testdata, exclude, func(name string) error {
+		return addFile(&files, name)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// walkUploads calls add for each file that uploadList would list.
+func walkUploads(cmdName string, extra []string, testdata bool, exclude []string, add func(name string) error) error {
 	if isFileCmd(cmdName) {
-		if err := addFile(&files, cmdName); err != nil {
-			return nil, err
+		if err := add(cmdName); err != nil {
+			return err
 		}
 	}
 	for _, p := range extra {
-		if err := addTree(&files, p); err != nil {
-			return nil, err
+		if err := walkTree(p, exclude, add); err != nil {
+			return err
 		}
 	}
 	if testdata {
 		dir, err := os.Getwd()
 		if err != nil {
-			return nil, err
+			return err
 		}
 		for {
 			td := filepath.Join(dir, "testdata")
 			if info, err := os.Stat(td); err == nil && info.IsDir() {
-				if err := addTree(&files, td); err != nil {
-					return nil, err
+				if err := walkTree(td, exclude, add); err != nil {
+					return err
 				}
 			}
 			if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
@@ -52,7 +67,7 @@ func uploadList(cmdName string, extra []string, testdata bool) ([]*File, error)
 			dir = parent
 		}
 	}
-	return files, nil
+	return nil
 }
 
 // isFileCmd reports whether the command name refers to a file
@@ -86,26 +101,62 @@ func isRegular(name string) bool {
 	return err == nil && info.Mode().IsRegular()
 }
 
-// addTree adds the file or directory tree rooted at name to files.
-func addTree(files *[]*File, name string) error {
+// addTree adds the file or directory tree rooted at name to files,
+// leaving out what matches the exclude patterns.
+func addTree(files *[]*File, name string, exclude []string) error {
+	return walkTree(name, exclude, func(file string) error {
+		return addFile(files, file)
+	})
+}
+
+// walkTree calls add for each file in the file or directory tree
+// rooted at name, leaving out what matches the exclude patterns.
+func walkTree(name string, exclude []string, add func(name string) error) error {
 	info, err := os.Stat(name)
 	if err != nil {
 		return err
 	}
 	if !info.IsDir() {
-		return addFile(files, name)
+		return add(name)
 	}
-	return filepath.WalkDir(name, func(path string, d fs.DirEntry, err error) error {
+	return filepath.WalkDir(name, func(file string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
+		if file != name {
+			rel, _ := filepath.Rel(name, file)
+			if excluded(filepath.ToSlash(rel), exclude) {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+		}
 		if d.Type().IsRegular() {
-			return addFile(files, path)
+			return add(file)
 		}
 		return nil
 	})
 }
 
+// excluded reports whether the file or directory rel, a slash-separated
+// path relative to the top of an uploaded tree, matches any of the
+// exclude patterns. A pattern (in the syntax of path.Match) containing
+// a slash must match all of rel; other patterns need only match the
+// last element.
+func excluded(rel string, exclude []string) bool {
+	for _, pattern := range exclude {
+		target := rel
+		if !strings.Contains(pattern, "/") {
+			target = path.Base(rel)
+		}
+		if ok, _ := path.Match(pattern, target); ok {
+			return true
+		}
+	}
+	return false
+}
+
 // addFile adds the single file name to files,
 // computing its SHA-256 hash and recording its absolute slash-form path.
 func addFile(files *[]*File, name string) error {
@@ -2,11 +2,12 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-package main
+package mote
 
 import (
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 )
@@ -88,9 +89,12 @@ func TestUploadList(t *testing.T) {
 	mkfile("a/b/testdata/deep.txt", "deep")
 	mkfile("a/b/prog", "binary")
 	mkfile("extra/data.txt", "extra")
+	mkfile("extra/data.log", "log")
+	mkfile("extra/big/blob", "big")
+	mkfile("testdata/sub/skip.txt", "skip")
 	t.Chdir(filepath.Join(mod, "a", "b"))
 
-	files, err := uploadList("./prog", []string{filepath.Join(mod, "extra")}, true)
+	files, err := uploadList("./prog", []string{filepath.Join(mod, "extra")}, true, []string{"*.log", "big", "sub/skip.txt"})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -110,7 +114,7 @@ func TestUploadList(t *testing.T) {
 	}
 
 	// Without a slash, the command is not uploaded.
-	files, err = uploadList("hostname", nil, false)
+	files, err = uploadList("hostname", nil, false, nil)
 	if err != nil || len(files) != 0 {
 		t.Errorf("uploadList(hostname) = %v, %v; want empty", files, err)
 	}
@@ -142,3 +146,39 @@ func TestRemotePath(t *testing.T) {
 		}
 	}
 }
+
+func TestMapPathEnv(t *testing.T) {
+	tmp := filepath.FromSlash("/tmp/mote-1")
+	got, err := mapPathEnv(tmp, []string{
+		"GOPROXY=file:///home/gopher/go/pkg/mod/cache/download",
+		"GOPROXY=file://C:/Users/gopher/go/pkg/mod/cache/download",
+		"DATA=/home/gopher/data",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"GOPROXY=" + fileURL(filepath.Join(tmp, filepath.FromSlash("home/gopher/go/pkg/mod/cache/download"))),
+		"GOPROXY=" + fileURL(filepath.Join(tmp, filepath.FromSlash("Users/gopher/go/pkg/mod/cache/download"))),
+		"DATA=" + filepath.Join(tmp, filepath.FromSlash("home/gopher/data")),
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("mapPathEnv:\nhave %q\nwant %q", got, want)
+	}
+	for _, bad := range []string{"NOVALUE", "=/x", "X=/a/../../etc", "X=file://../x"} {
+		if got, err := mapPathEnv(tmp, []string{bad}); err == nil {
+			t.Errorf("mapPathEnv(%q) = %q, want error", bad, got)
+		}
+	}
+}
+
+func TestFileURL(t *testing.T) {
+	for _, tt := range []struct{ in, want string }{
+		{"/tmp/x", "file:///tmp/x"},
+		{"C:/tmp/x", "file:///C:/tmp/x"},
+	} {
+		if got := fileURL(tt.in); got != tt.want {
+			t.Errorf("fileURL(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+)
+
+// watchInterval is how often -watch looks for changed files.
+var watchInterval = 500 * time.Millisecond
+
+// watchStamp returns a summary of the files that uploadList would list
+// for the command: their names, sizes, and modification times. It
+// changes when any of them does, or when one appears or disappears,
+// without the cost of hashing them.
+func watchStamp(cmdName string, extra []string, testdata bool, exclude []string) string {
+	var b strings.Builder
+	err := walkUploads(cmdName, extra, testdata, exclude, func(name string) error {
+		info, err := os.Stat(name)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&b, "%s %d %d\n", name, info.Size(), info.ModTime().UnixNano())
+		return nil
+	})
+	if err != nil {
+		// A file missing in the middle of a rebuild is a change too.
+		fmt.Fprintf(&b, "error: %v\n", err)
+	}
+	return b.String()
+}
+
+// settle waits for the files summarized by stamp to stop changing,
+// as a binary being written by the linker does, and returns their
+// final stamp. It returns early if ctx is done.
+func settle(ctx context.Context, stamp func() string) string {
+	last := stamp()
+	for {
+		select {
+		case <-ctx.Done():
+			return last
+		case <-time.After(watchInterval):
+		}
+		s := stamp()
+		if s == last {
+			return s
+		}
+		last = s
+	}
+}
+
+// waitChange waits until the stamp of the files differs from old,
+// then for them to settle, and returns the new stamp. It returns
+// early if ctx is done.
+func waitChange(ctx context.Context, old string, stamp func() string) string {
+	for {
+		select {
+		case <-ctx.Done():
+			return old
+		case <-time.After(watchInterval):
+		}
+		if stamp() != old {
+			return settle(ctx, stamp)
+		}
+	}
+}
+
+// watchRun implements mote -watch: it runs args on the server at url,
+// where conn is already connected, and runs it again, over a new
+// connection to the same server, whenever the command's binary or its
+// uploaded files change, killing the previous run if it is still going.
+// Only changed files are uploaded again, since the server caches the
+// rest. An interrupt stops the current run and ends the watch.
+func watchRun(conn *Conn, a *alias, url string, args []string, dir string) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	defer signal.Stop(sig)
+	go func() {
+		<-sig
+		cancel()
+		<-sig
+		os.Exit(1)
+	}()
+
+	stamp := func() string { return watchStamp(args[0], uploads, *testData, a.Exclude) }
+	current := stamp()
+	for ctx.Err() == nil {
+		if conn == nil {
+			var err error
+			if conn, err = dialServer(url); err != nil {
+				log.Print(err)
+			}
+		}
+		changed := make(chan string, 1)
+		if conn != nil {
+			// Run the command, stopping it early if the files change.
+			runCtx, stop := context.WithCancel(ctx)
+			go func() {
+				changed <- waitChange(runCtx, current, stamp)
+				stop()
+			}()
+			watchOnce(runCtx, conn, a, args, dir)
+			stop()
+			conn.Close()
+			conn = nil
+			if s := <-changed; s != current {
+				current = s
+				log.Printf("files changed; running again")
+				continue
+			}
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		log.Printf("waiting for changes")
+		current = waitChange(ctx, current, stamp)
+		if ctx.Err() == nil {
+			log.Printf("files changed; running again")
+		}
+	}
+}
+
+// watchOnce runs args once for watchRun, reporting problems instead of
+// exiting, so that the watch can go on.
+func watchOnce(ctx context.Context, conn *Conn, a *alias, args []string, dir string) {
+	if isFileCmd(args[0]) {
+		if err := checkBinary(conn, args[0]); err != nil {
+			log.Print(err)
+			return
+		}
+	}
+	files, err := uploadList(args[0], uploads, *testData, a.Exclude)
+	if err != nil {
+		log.Print(err)
+		return
+	}
+	if a.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(a.Timeout))
+		defer cancel()
+	}
+	w, err := conn.Run(ctx, runExec(args, dir, files, a))
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		log.Printf("timed out after %v", time.Duration(a.Timeout))
+		return
+	case errors.Is(err, context.Canceled):
+		return
+	case err != nil:
+		log.Print(conn.abort(err))
+		return
+	}
+	reportWait(w)
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		log.Printf("remote command timed out after %v: %s", time.Duration(a.Timeout), w.Status)
+	case ctx.Err() != nil:
+		log.Printf("remote command stopped: %s", w.Status)
+	case w.Code != 0:
+		log.Printf("remote command failed: %s", w.Status)
+	}
+}
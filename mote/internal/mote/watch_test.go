@@ -0,0 +1,75 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mote
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatchStamp(t *testing.T) {
+	dir := t.TempDir()
+	prog := filepath.Join(dir, "prog")
+	data := filepath.Join(dir, "data")
+	for _, name := range []string{prog, filepath.Join(data, "a"), filepath.Join(data, "skip.tmp")} {
+		os.MkdirAll(filepath.Dir(name), 0o777)
+		if err := os.WriteFile(name, []byte("1"), 0o777); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stamp := func() string { return watchStamp(prog, []string{data}, false, []string{"*.tmp"}) }
+	s := stamp()
+	if s != stamp() {
+		t.Fatalf("stamp changed with no changes")
+	}
+	// An excluded file does not count.
+	os.WriteFile(filepath.Join(data, "skip.tmp"), []byte("22"), 0o666)
+	if stamp() != s {
+		t.Errorf("stamp changed for excluded file")
+	}
+	for _, change := range []struct {
+		name string
+		do   func() error
+	}{
+		{"rewrite binary", func() error { return os.WriteFile(prog, []byte("22"), 0o777) }},
+		{"add file", func() error { return os.WriteFile(filepath.Join(data, "b"), nil, 0o666) }},
+		{"remove file", func() error { return os.Remove(filepath.Join(data, "a")) }},
+		{"remove binary", func() error { return os.Remove(prog) }},
+	} {
+		if err := change.do(); err != nil {
+			t.Fatal(err)
+		}
+		if s2 := stamp(); s2 == s {
+			t.Errorf("%s: stamp did not change", change.name)
+		} else {
+			s = s2
+		}
+	}
+}
+
+func TestWaitChange(t *testing.T) {
+	defer func(d time.Duration) { watchInterval = d }(watchInterval)
+	watchInterval = time.Millisecond
+
+	// Changes are reported once the files stop changing.
+	n := 0
+	stamp := func() string {
+		n++
+		return []string{"old", "old", "new1", "new2", "new3", "new3"}[min(n-1, 5)]
+	}
+	if got := waitChange(t.Context(), "old", stamp); got != "new3" {
+		t.Errorf("waitChange = %q, want %q", got, "new3")
+	}
+
+	// A canceled wait returns the old stamp.
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	if got := waitChange(ctx, "old", func() string { return "old" }); got != "old" {
+		t.Errorf("canceled waitChange = %q, want %q", got, "old")
+	}
+}
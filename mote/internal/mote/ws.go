@@ -0,0 +1,173 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mote
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/coder/websocket"
+)
+
+// The WebSocket transport reaches servers that only HTTP can get to,
+// such as machines behind an HTTP reverse proxy. The server serves
+// ws://host:port/path, answering WebSocket upgrades at the path, and
+// the client dials ws:// or, through a proxy that terminates TLS,
+// wss://, going through any HTTP proxy named by $HTTP_PROXY,
+// $HTTPS_PROXY, and $NO_PROXY, as every Go HTTP client does.
+//
+// The WebSocket carries the same byte stream as a TCP connection,
+// and like a TCP connection it is authenticated and encrypted by the
+// password (see secure.go): a reverse proxy, or anyone else who can
+// reach the URL, sees nothing but an encrypted stream.
+
+// checkWSURL checks that u is a well-formed ws:// or wss:// URL.
+func checkWSURL(u *url.URL) error {
+	if u.Opaque != "" || u.RawQuery != "" || u.Fragment != "" {
+		return fmt.Errorf("%s server URL must have the form %s://host:port/path", u.Scheme, u.Scheme)
+	}
+	return nil
+}
+
+// wsKey returns the password.txt key for the ws:// or wss:// URL u:
+// the URL without any trailing slash. As with tcp://, a client and a
+// server may know the same server by different URLs, especially with
+// a proxy between them, and each looks up the one it uses.
+func wsKey(u *url.URL) string {
+	return u.Scheme + "://" + u.Host + strings.TrimSuffix(u.Path, "/")
+}
+
+// dialWS connects to a ws:// or wss:// server.
+func dialWS(u *url.URL) (io.ReadWriteCloser, string, error) {
+	if err := checkWSURL(u); err != nil {
+		return nil, "", err
+	}
+	if u.Hostname() == "" {
+		return nil, "", fmt.Errorf("%s server URL must include a host", u.Scheme)
+	}
+	password, err := lookupPassword(wsKey(u))
+	if err != nil {
+		return nil, "", err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), handshakeTimeout)
+	defer cancel()
+	// A nil HTTPClient means http.DefaultClient, which uses the proxy
+	// environment variables.
+	c, _, err := websocket.Dial(ctx, u.String(), nil)
+	if err != nil {
+		return nil, "", err
+	}
+	return websocket.NetConn(context.Background(), c, websocket.MessageBinary), password, nil
+}
+
+// serveWS implements "mote serve ws://host:port/path".
+func serveWS(rawURL string) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if u.Scheme == "wss" {
+		log.Fatalf("cannot serve wss:// directly: serve ws:// behind a proxy that terminates TLS")
+	}
+	if err := checkWSURL(u); err != nil {
+		log.Fatal(err)
+	}
+	password, err := lookupPassword(wsKey(u))
+	if err != nil {
+		log.Fatal(err)
+	}
+	ln, err := net.Listen("tcp", u.Host)
+	if err != nil {
+		log.Fatal(err)
+	}
+	host := u.Hostname()
+	if host == "" {
+		host, _ = os.Hostname()
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	log.Printf("serving ws://%s%s", net.JoinHostPort(host, fmt.Sprint(port)), u.Path)
+	if *metricsAddr != "" {
+		if _, err := serveMetrics(*metricsAddr); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if err := serveWSListener(ln, wsHandler(u.Path, password)); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// serveWSListener serves h over HTTP on ln, draining h's sessions on
+// SIGTERM or SIGINT just as serveListener does. The sessions have left
+// HTTP behind, hijacking their connections, so the http.Server's
+// Shutdown stops only the listener and the requests not yet upgraded;
+// the drain ends the sessions, and h says when they are done.
+func serveWSListener(ln net.Listener, h *wsServer) error {
+	srv := &http.Server{Handler: h, ReadHeaderTimeout: handshakeTimeout}
+	shutdown := make(chan struct{})
+	stop := func() {
+		go func() {
+			srv.Shutdown(context.Background())
+			close(shutdown)
+		}()
+	}
+	return serveDraining(stop, func() error {
+		err := srv.Serve(ln)
+		if err == http.ErrServerClosed {
+			// Once Shutdown returns, every request has upgraded or
+			// ended, so no new session can start.
+			<-shutdown
+		}
+		h.sessions.Wait()
+		return err
+	})
+}
+
+// A wsServer is an HTTP handler serving mote sessions over WebSocket
+// connections upgraded at path, with or without a trailing slash, to
+// match wsKey. Like serveListener, it serves at most maxSessions at
+// once; requests beyond that wait.
+type wsServer struct {
+	path     string
+	password string
+	sem      chan struct{}
+	sessions sync.WaitGroup
+}
+
+// wsHandler returns a wsServer for path, using password.
+func wsHandler(path, password string) *wsServer {
+	return &wsServer{
+		path:     strings.TrimSuffix(path, "/"),
+		password: password,
+		sem:      make(chan struct{}, maxSessions),
+	}
+}
+
+func (s *wsServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if strings.TrimSuffix(r.URL.Path, "/") != s.path {
+		http.NotFound(w, r)
+		return
+	}
+	s.sessions.Add(1)
+	defer s.sessions.Done()
+	s.sem <- struct{}{}
+	defer func() { <-s.sem }()
+	c, err := websocket.Accept(w, r, nil)
+	if err != nil {
+		return // Accept has replied
+	}
+	conn := websocket.NetConn(context.Background(), c, websocket.MessageBinary)
+	defer conn.Close()
+	if err := serve(conn, s.password, nil); err != nil {
+		log.Print(err)
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mote
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWSTransport(t *testing.T) {
+	setupDirs(t)
+	srv := httptest.NewServer(wsHandler("/mote", "s3cret"))
+	defer srv.Close()
+	url := "ws://" + strings.TrimPrefix(srv.URL, "http://") + "/mote"
+	if err := setPassword(url, "s3cret"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A trailing slash names the same server.
+	conn, err := dialServer(url + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	runConn(t, conn, []string{"echo", "over websocket"}, "over websocket\n")
+
+	// Plain HTTP requests and other paths are turned away.
+	resp, err := http.Get(srv.URL + "/mote")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusSwitchingProtocols || resp.StatusCode == http.StatusOK {
+		t.Errorf("GET without upgrade: %s", resp.Status)
+	}
+	if _, err := dialServer("ws://" + strings.TrimPrefix(srv.URL, "http://") + "/other"); err == nil {
+		t.Errorf("dialServer at wrong path succeeded")
+	}
+}
+
+func TestWSWrongPassword(t *testing.T) {
+	setupDirs(t)
+	srv := httptest.NewServer(wsHandler("/", "s3cret"))
+	defer srv.Close()
+	url := "ws://" + strings.TrimPrefix(srv.URL, "http://")
+	if err := setPassword(url, "wrong"); err != nil {
+		t.Fatal(err)
+	}
+	if conn, err := dialServer(url); err == nil {
+		conn.Close()
+		t.Fatalf("dialServer with wrong password succeeded")
+	}
+}